@@ -0,0 +1,102 @@
+package strata
+
+import (
+	"encoding/base64"
+	"fmt"
+	"mime"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// hashedAssetName returns an asset's file name with a content hash
+// inserted before its extension (e.g., "card/bg.png" -> "bg.1a2b3c4d5e6f7a8b.png").
+func hashedAssetName(assetPath string, data []byte) string {
+	base := path.Base(assetPath)
+	ext := path.Ext(base)
+	return strings.TrimSuffix(base, ext) + "." + contentHash(data) + ext
+}
+
+// addAsset records an asset under its hashed name in the config's
+// AssetDir, returning that path.
+func (c *compiler) addAsset(assetPath string, data []byte) string {
+	name := path.Join(c.config.AssetDir, hashedAssetName(assetPath, data))
+	if c.assets == nil {
+		c.assets = make(map[string][]byte)
+	}
+	c.assets[name] = data
+	return name
+}
+
+// assetTypes maps asset extensions to media types for data URIs. It takes
+// precedence over the mime package, whose table varies between systems.
+var assetTypes = map[string]string{
+	".avif":  "image/avif",
+	".gif":   "image/gif",
+	".jpeg":  "image/jpeg",
+	".jpg":   "image/jpeg",
+	".otf":   "font/otf",
+	".png":   "image/png",
+	".svg":   "image/svg+xml",
+	".ttf":   "font/ttf",
+	".webp":  "image/webp",
+	".woff":  "font/woff",
+	".woff2": "font/woff2",
+}
+
+// assetType returns the media type of an asset from its extension.
+func assetType(assetPath string) string {
+	ext := strings.ToLower(path.Ext(assetPath))
+	if t, ok := assetTypes[ext]; ok {
+		return t
+	}
+	if t := mime.TypeByExtension(ext); t != "" {
+		return t
+	}
+	return "application/octet-stream"
+}
+
+// dataURI encodes an asset as a data URI: SVGs URL-encoded, which stays
+// smaller and readable, and everything else base64-encoded.
+func dataURI(assetPath string, data []byte) string {
+	mediaType := assetType(assetPath)
+	if mediaType == "image/svg+xml" {
+		return "data:" + mediaType + "," + urlEncodeSVG(data)
+	}
+	return "data:" + mediaType + ";base64," + base64.StdEncoding.EncodeToString(data)
+}
+
+// urlEncodeSVG percent-encodes SVG markup for a data URI, leaving
+// characters that are safe in both quoted and unquoted url() as-is.
+func urlEncodeSVG(data []byte) string {
+	const safe = "-._~!$&*+,;=:@/?"
+	var b strings.Builder
+	for _, c := range data {
+		switch {
+		case c == '\n' || c == '\r' || c == '\t':
+			b.WriteString("%20") // line breaks are insignificant in markup
+		case (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9'),
+			strings.IndexByte(safe, c) >= 0:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+// WriteAssets writes Result.Assets to dir, creating directories as needed.
+// Serve dir at Config.BaseURL.
+func (r *Result) WriteAssets(dir string) error {
+	for name, data := range r.Assets {
+		target := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+			return fmt.Errorf("write asset %s: %w", name, err)
+		}
+		if err := os.WriteFile(target, data, 0o644); err != nil {
+			return fmt.Errorf("write asset %s: %w", name, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,262 @@
+package strata
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestHashedAssetName(t *testing.T) {
+	t.Parallel()
+
+	data := []byte("png")
+	got := hashedAssetName("card/bg.png", data)
+	want := "bg." + contentHash(data) + ".png"
+	if got != want {
+		t.Errorf("hashedAssetName() = %q, want %q", got, want)
+	}
+
+	if got := hashedAssetName("fonts/LICENSE", nil); !strings.HasPrefix(got, "LICENSE.") {
+		t.Errorf("hashedAssetName() without extension = %q, want LICENSE.<hash>", got)
+	}
+}
+
+func TestCompile_hash_assets(t *testing.T) {
+	t.Parallel()
+
+	bg := []byte("png bytes")
+	font := []byte("woff2 bytes")
+	testFS := fstest.MapFS{
+		"card/card.css": {Data: []byte(
+			".card { background: url(./bg.png); }\n" +
+				".card-alt { background: url(bg.png?v=1); }\n" +
+				".card-ext { background: url(https://example.com/x.png); }\n",
+		)},
+		"card/bg.png":       {Data: bg},
+		"fonts.css":         {Data: []byte(`@font-face { src: url("fonts/inter.woff2") format("woff2"); }`)},
+		"fonts/inter.woff2": {Data: font},
+	}
+
+	got, err := Config{HashAssets: true, BaseURL: "/static", AssetDir: "assets"}.Compile(Source{FS: testFS})
+	if err != nil {
+		t.Fatalf("Config.Compile() error = %v, want nil", err)
+	}
+
+	bgName := "assets/bg." + contentHash(bg) + ".png"
+	fontName := "assets/inter." + contentHash(font) + ".woff2"
+	if len(got.Assets) != 2 || string(got.Assets[bgName]) != string(bg) || string(got.Assets[fontName]) != string(font) {
+		t.Errorf("Result.Assets keys = %v, want %s and %s", mapKeys(got.Assets), bgName, fontName)
+	}
+
+	css := got.CSS()
+	for _, want := range []string{
+		"url(/static/" + bgName + ")",
+		"url(/static/" + bgName + "?v=1)",
+		"url(https://example.com/x.png)",
+		`url("/static/` + fontName + `")`,
+	} {
+		if !strings.Contains(css, want) {
+			t.Errorf("Result.CSS() missing %q, got:\n%s", want, css)
+		}
+	}
+}
+
+func TestCompile_hash_assets_missing(t *testing.T) {
+	t.Parallel()
+
+	testFS := fstest.MapFS{
+		"card/card.css": {Data: []byte(".card {\n\tbackground: url(./missing.png);\n}\n")},
+	}
+
+	_, err := Config{HashAssets: true}.Compile(Source{FS: testFS})
+	if err == nil {
+		t.Fatal("Config.Compile() error = nil, want error")
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Config.Compile() error = %v, want fs.ErrNotExist", err)
+	}
+	if !strings.Contains(err.Error(), "card/card.css:2: asset ./missing.png") {
+		t.Errorf("Config.Compile() error = %q, want error naming file, line and asset", err.Error())
+	}
+}
+
+func TestDataURI(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		path string
+		data string
+		want string
+	}{
+		{
+			name: "svg url-encoded",
+			path: "icons/check.svg",
+			data: "<svg xmlns=\"http://www.w3.org/2000/svg\">\n<path d=\"M0 0\"/></svg>",
+			want: "data:image/svg+xml,%3Csvg%20xmlns=%22http://www.w3.org/2000/svg%22%3E%20%3Cpath%20d=%22M0%200%22/%3E%3C/svg%3E",
+		},
+		{
+			name: "png base64",
+			path: "bg.PNG",
+			data: "png",
+			want: "data:image/png;base64,cG5n",
+		},
+		{
+			name: "font",
+			path: "inter.woff2",
+			data: "w",
+			want: "data:font/woff2;base64,dw==",
+		},
+		{
+			name: "unknown extension",
+			path: "blob.strata-unknown",
+			data: "x",
+			want: "data:application/octet-stream;base64,eA==",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := dataURI(tt.path, []byte(tt.data)); got != tt.want {
+				t.Errorf("dataURI() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompile_inline_assets(t *testing.T) {
+	t.Parallel()
+
+	large := []byte(strings.Repeat("x", 16))
+	testFS := fstest.MapFS{
+		"card/card.css": {Data: []byte(
+			".a { background: url(./dot.png); }\n" +
+				".b { background: url(./large.png); }\n" +
+				".c { background: url(./sprite.svg#icon); }\n",
+		)},
+		"card/dot.png":    {Data: []byte("png")},
+		"card/large.png":  {Data: large},
+		"card/sprite.svg": {Data: []byte("<svg/>")},
+	}
+
+	got, err := Config{InlineLimit: 8, HashAssets: true, AssetDir: "assets"}.Compile(Source{FS: testFS})
+	if err != nil {
+		t.Fatalf("Config.Compile() error = %v, want nil", err)
+	}
+
+	largeName := "assets/large." + contentHash(large) + ".png"
+	spriteName := "assets/sprite." + contentHash([]byte("<svg/>")) + ".svg"
+	if len(got.Assets) != 2 || got.Assets[largeName] == nil || got.Assets[spriteName] == nil {
+		t.Errorf("Result.Assets keys = %v, want %s and %s", mapKeys(got.Assets), largeName, spriteName)
+	}
+
+	css := got.CSS()
+	for _, want := range []string{
+		"url(data:image/png;base64,cG5n)",
+		"url(" + largeName + ")",
+		"url(" + spriteName + "#icon)",
+	} {
+		if !strings.Contains(css, want) {
+			t.Errorf("Result.CSS() missing %q, got:\n%s", want, css)
+		}
+	}
+}
+
+func TestCompile_inline_assets_without_hashing(t *testing.T) {
+	t.Parallel()
+
+	testFS := fstest.MapFS{
+		"card/card.css": {Data: []byte(
+			".a { background: url(dot.png); }\n" +
+				".b { background: url(./large.png); }\n" +
+				".c { background: url(missing.png); }\n",
+		)},
+		"card/dot.png":   {Data: []byte("png")},
+		"card/large.png": {Data: []byte(strings.Repeat("x", 16))},
+	}
+
+	tests := []struct {
+		name   string
+		config Config
+		want   []string
+	}{
+		{
+			name:   "inline_only",
+			config: Config{InlineLimit: 8, BaseURL: "/static"},
+			want: []string{
+				"url(data:image/png;base64,cG5n)",
+				"url(./large.png)",
+				"url(missing.png)",
+			},
+		},
+		{
+			name:   "inline_and_rewrite",
+			config: Config{InlineLimit: 8, RewriteURLs: true, BaseURL: "/static"},
+			want: []string{
+				"url(data:image/png;base64,cG5n)",
+				"url(/static/card/large.png)",
+				"url(/static/card/missing.png)",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := tt.config.Compile(Source{FS: testFS})
+			if err != nil {
+				t.Fatalf("Config.Compile() error = %v, want nil", err)
+			}
+			if len(got.Assets) != 0 {
+				t.Errorf("Result.Assets keys = %v, want none", mapKeys(got.Assets))
+			}
+
+			css := got.CSS()
+			for _, want := range tt.want {
+				if !strings.Contains(css, want) {
+					t.Errorf("Result.CSS() missing %q, got:\n%s", want, css)
+				}
+			}
+		})
+	}
+}
+
+func TestResult_WriteAssets(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	r := &Result{Assets: map[string][]byte{
+		"assets/bg.123.png": []byte("png"),
+		"top.456.svg":       []byte("svg"),
+	}}
+
+	if err := r.WriteAssets(dir); err != nil {
+		t.Fatalf("Result.WriteAssets() error = %v, want nil", err)
+	}
+
+	for name, want := range r.Assets {
+		got, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(name)))
+		if err != nil {
+			t.Fatalf("ReadFile(%s) error = %v", name, err)
+		}
+		if string(got) != string(want) {
+			t.Errorf("asset %s = %q, want %q", name, got, want)
+		}
+	}
+}
+
+// mapKeys returns the keys of m, for test failure messages.
+func mapKeys(m map[string][]byte) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	return keys
+}
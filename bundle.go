@@ -0,0 +1,56 @@
+package strata
+
+import "fmt"
+
+// Bundle is a named set of sources compiled into its own stylesheet, such
+// as the styles shared by every page or those of a single route.
+type Bundle struct {
+	// Name identifies the bundle, such as "common" or "page.admin".
+	Name string
+
+	// Sources are compiled in order, as by Compile.
+	Sources []Source
+}
+
+// CompileBundles compiles each bundle like Compile and returns the results
+// by bundle name.
+func CompileBundles(bundles ...Bundle) (map[string]*Result, error) {
+	return Config{}.CompileBundles(bundles...)
+}
+
+// CompileBundles compiles each bundle separately and returns the results
+// by bundle name. Every result shares one Header declaring the layers of
+// all bundles, in bundle order, so a page can load the common bundle plus
+// its own route bundle without reordering the cascade:
+//
+//	res, err := strata.CompileBundles(
+//	    strata.Bundle{Name: "common", Sources: []strata.Source{styles, components}},
+//	    strata.Bundle{Name: "page.admin", Sources: []strata.Source{admin}},
+//	)
+//	res["common"].CSS()         // header + common layers
+//	res["page.admin"].Body()    // admin layers only
+func (c Config) CompileBundles(bundles ...Bundle) (map[string]*Result, error) {
+	results := make(map[string]*Result, len(bundles))
+	var layers []Layer
+	for _, b := range bundles {
+		if _, exists := results[b.Name]; exists {
+			return nil, fmt.Errorf("bundle %s: duplicate name", b.Name)
+		}
+		r, err := c.Compile(b.Sources...)
+		if err != nil {
+			return nil, fmt.Errorf("bundle %s: %w", b.Name, err)
+		}
+		results[b.Name] = r
+		layers = append(layers, r.Layers...)
+	}
+
+	// Layers repeated across bundles are declared once, where first seen.
+	// Full dotted names are declared in every output mode, since a nested
+	// block only orders its sub-layers within the bundle that contains it.
+	order := uniqueNames(layerNames(layers))
+	for _, r := range results {
+		r.order = order
+	}
+
+	return results, nil
+}
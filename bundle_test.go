@@ -0,0 +1,142 @@
+package strata
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestConfig_CompileBundles(t *testing.T) {
+	t.Parallel()
+
+	stylesFS := fstest.MapFS{
+		"reset.css":          {Data: []byte("reset")},
+		"components/btn.css": {Data: []byte("btn")},
+	}
+	adminFS := fstest.MapFS{
+		"admin.css": {Data: []byte("admin")},
+	}
+	blogFS := fstest.MapFS{
+		"blog.css": {Data: []byte("blog")},
+	}
+
+	tests := []struct {
+		name       string
+		output     Output
+		wantHeader string
+	}{
+		{
+			name:       "flat",
+			output:     OutputFlat,
+			wantHeader: "@layer components, reset, tw, routes.admin, routes.blog;\n",
+		},
+		{
+			name:       "nested",
+			output:     OutputNested,
+			wantHeader: "@layer components, reset, tw, routes.admin, routes.blog;\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := Config{Output: tt.output}.CompileBundles(
+				Bundle{Name: "common", Sources: []Source{{FS: stylesFS}, ExternalLayer("tw")}},
+				Bundle{Name: "page.admin", Sources: []Source{{FS: adminFS, Layer: "routes.admin"}}},
+				Bundle{Name: "page.blog", Sources: []Source{{FS: blogFS, Layer: "routes.blog"}, ExternalLayer("tw")}},
+			)
+			if err != nil {
+				t.Fatalf("Config.CompileBundles() error = %v, want nil", err)
+			}
+			if len(got) != 3 {
+				t.Fatalf("Config.CompileBundles() returned %d bundles, want 3", len(got))
+			}
+
+			for name, r := range got {
+				if h := r.Header(); h != tt.wantHeader {
+					t.Errorf("bundle %s Header() = %q, want %q", name, h, tt.wantHeader)
+				}
+			}
+
+			common := got["common"].Body()
+			if !strings.Contains(common, "btn") || strings.Contains(common, "admin") {
+				t.Errorf("common Body() = %q, want only common layers", common)
+			}
+			if admin := got["page.admin"].CSS(); admin != tt.wantHeader+got["page.admin"].Body() ||
+				!strings.Contains(admin, "admin") || strings.Contains(admin, "btn") {
+				t.Errorf("page.admin CSS() = %q, want header and admin layers only", admin)
+			}
+		})
+	}
+}
+
+func TestConfig_CompileBundles_nested(t *testing.T) {
+	t.Parallel()
+
+	commonFS := fstest.MapFS{
+		"components/btn/btn.css":   {Data: []byte("btn")},
+		"components/card/card.css": {Data: []byte("card")},
+	}
+	adminFS := fstest.MapFS{
+		"table.css": {Data: []byte("table")},
+	}
+
+	got, err := Config{Output: OutputNested}.CompileBundles(
+		Bundle{Name: "common", Sources: []Source{{FS: commonFS}}},
+		Bundle{Name: "page.admin", Sources: []Source{{FS: adminFS, Layer: "components.admin"}}},
+	)
+	if err != nil {
+		t.Fatalf("Config.CompileBundles() error = %v, want nil", err)
+	}
+
+	// Both bundles nest under components, so the sub-layer order must come
+	// from the shared header rather than whichever bundle loads first
+	wantHeader := "@layer components.btn, components.card, components.admin;\n"
+	for _, name := range []string{"common", "page.admin"} {
+		if h := got[name].Header(); h != wantHeader {
+			t.Errorf("bundle %s Header() = %q, want %q", name, h, wantHeader)
+		}
+	}
+
+	admin := got["page.admin"].Body()
+	if !strings.Contains(admin, "@layer components {") || !strings.Contains(admin, "table") ||
+		strings.Contains(admin, "btn") {
+		t.Errorf("page.admin Body() = %q, want nested admin layer only", admin)
+	}
+}
+
+func TestConfig_CompileBundles_errors(t *testing.T) {
+	t.Parallel()
+
+	testFS := fstest.MapFS{"a.css": {Data: []byte("a")}}
+	brokenFS := fstest.MapFS{"a.css.tmpl": {Data: []byte("{{")}}
+
+	tests := []struct {
+		name    string
+		bundles []Bundle
+		wantErr string
+	}{
+		{
+			name:    "duplicate name",
+			bundles: []Bundle{{Name: "common", Sources: []Source{{FS: testFS}}}, {Name: "common"}},
+			wantErr: "bundle common: duplicate name",
+		},
+		{
+			name:    "source error",
+			bundles: []Bundle{{Name: "page", Sources: []Source{{FS: brokenFS}}}},
+			wantErr: "bundle page: parse template a.css.tmpl",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := CompileBundles(tt.bundles...)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("CompileBundles() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
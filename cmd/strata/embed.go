@@ -0,0 +1,90 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"go/format"
+	"io"
+	"os"
+	"path/filepath"
+
+	strata "github.com/rlebel12/strata-go"
+)
+
+// runEmbed implements "strata embed": it builds the sources and writes the
+// CSS file plus a Go file that embeds it and declares its Hash and SRI.
+// With -check, it writes nothing and fails if either file is stale.
+func runEmbed(args []string, stderr io.Writer) error {
+	flags := flag.NewFlagSet("embed", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+
+	var sources sourceFlags
+	flags.Var(&sources, "src", "source `dir` or prefix=dir, in cascade order (repeatable)")
+	out := flags.String("o", "strata_embed.go", "output Go `file`")
+	cssName := flags.String("css", "styles.css", "output CSS file `name`, written next to the Go file")
+	pkg := flags.String("pkg", os.Getenv("GOPACKAGE"), "package `name` (default $GOPACKAGE)")
+	check := flags.Bool("check", false, "fail if the generated files are stale instead of writing them")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if len(sources) == 0 {
+		return errors.New("embed: at least one -src is required")
+	}
+	if *pkg == "" {
+		return errors.New("embed: -pkg is required outside go generate")
+	}
+	if filepath.Base(*cssName) != *cssName {
+		return errors.New("embed: -css must be a file name, not a path")
+	}
+
+	css, hash, err := strata.BuildWithHash(sources...)
+	if err != nil {
+		return fmt.Errorf("embed: %w", err)
+	}
+
+	src, err := generateEmbed(*pkg, *cssName, css, hash)
+	if err != nil {
+		return fmt.Errorf("embed: %w", err)
+	}
+
+	files := []struct {
+		name    string
+		content []byte
+	}{
+		{filepath.Join(filepath.Dir(*out), *cssName), []byte(css)},
+		{*out, src},
+	}
+	for _, f := range files {
+		if *check {
+			current, err := os.ReadFile(f.name)
+			if err != nil || !bytes.Equal(current, f.content) {
+				return fmt.Errorf("embed: %s is stale; run go generate", f.name)
+			}
+			continue
+		}
+		if err := os.WriteFile(f.name, f.content, 0o644); err != nil {
+			return fmt.Errorf("embed: %w", err)
+		}
+	}
+	return nil
+}
+
+// generateEmbed returns formatted Go source that embeds cssName as CSS and
+// declares its Hash and SRI.
+func generateEmbed(pkg, cssName, css, hash string) ([]byte, error) {
+	var b bytes.Buffer
+	b.WriteString("// Code generated by strata embed; DO NOT EDIT.\n\n")
+	fmt.Fprintf(&b, "package %s\n\n", pkg)
+	b.WriteString("import _ \"embed\"\n\n")
+	b.WriteString("// CSS is the built stylesheet.\n//\n")
+	fmt.Fprintf(&b, "//go:embed %s\n", cssName)
+	b.WriteString("var CSS string\n\n")
+	b.WriteString("// Hash is the content hash of CSS, for cache-busting filenames.\n")
+	fmt.Fprintf(&b, "const Hash = %q\n\n", hash)
+	b.WriteString("// SRI is the Subresource Integrity value of CSS.\n")
+	fmt.Fprintf(&b, "const SRI = %q\n", strata.Integrity(css))
+	return format.Source(b.Bytes())
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	strata "github.com/rlebel12/strata-go"
+)
+
+func TestGenerateEmbed(t *testing.T) {
+	t.Parallel()
+
+	css := "@layer reset;\n@layer reset {\n* {}\n}\n"
+	got, err := generateEmbed("styles", "styles.css", css, strata.Hash(css))
+	if err != nil {
+		t.Fatalf("generateEmbed() error = %v, want nil", err)
+	}
+
+	want := `// Code generated by strata embed; DO NOT EDIT.
+
+package styles
+
+import _ "embed"
+
+// CSS is the built stylesheet.
+//
+//go:embed styles.css
+var CSS string
+
+// Hash is the content hash of CSS, for cache-busting filenames.
+const Hash = "` + strata.Hash(css) + `"
+
+// SRI is the Subresource Integrity value of CSS.
+const SRI = "` + strata.Integrity(css) + `"
+`
+	if string(got) != want {
+		t.Errorf("generateEmbed() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestRun_embed(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	srcDir := filepath.Join(dir, "css")
+	writeFile(t, filepath.Join(srcDir, "reset.css"), "* {}")
+	out := filepath.Join(dir, "styles_embed.go")
+	args := []string{"embed", "-src", srcDir, "-pkg", "styles", "-o", out}
+
+	// Generated files are missing, so the check fails
+	err := run(append(args, "-check"), io.Discard)
+	if err == nil || !strings.Contains(err.Error(), "stale") {
+		t.Fatalf("run(-check) before generate error = %v, want stale error", err)
+	}
+
+	if err := run(args, io.Discard); err != nil {
+		t.Fatalf("run() error = %v, want nil", err)
+	}
+
+	css, err := os.ReadFile(filepath.Join(dir, "styles.css"))
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+	if want := "@layer reset;\n@layer reset {\n* {}\n}\n"; string(css) != want {
+		t.Errorf("styles.css = %q, want %q", css, want)
+	}
+
+	// Fresh output passes the check
+	if err := run(append(args, "-check"), io.Discard); err != nil {
+		t.Errorf("run(-check) after generate error = %v, want nil", err)
+	}
+
+	// Changed sources make the output stale
+	writeFile(t, filepath.Join(srcDir, "reset.css"), "* { margin: 0; }")
+	err = run(append(args, "-check"), io.Discard)
+	if err == nil || !strings.Contains(err.Error(), "styles.css is stale") {
+		t.Errorf("run(-check) after source change error = %v, want stale error", err)
+	}
+}
+
+func TestRun_embed_css_path(t *testing.T) {
+	t.Parallel()
+
+	err := run([]string{"embed", "-src", ".", "-pkg", "x", "-css", "out/styles.css"}, io.Discard)
+	if err == nil || !strings.Contains(err.Error(), "file name") {
+		t.Errorf("run() error = %v, want file name error", err)
+	}
+}
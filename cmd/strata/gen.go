@@ -0,0 +1,114 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"go/format"
+	"io"
+	"os"
+	"strings"
+
+	strata "github.com/rlebel12/strata-go"
+)
+
+// runGen implements "strata gen": it compiles the sources and writes a Go
+// file declaring a constant for every layer name and every custom property
+// declared in the tokens layer.
+func runGen(args []string, stderr io.Writer) error {
+	flags := flag.NewFlagSet("gen", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+
+	var sources sourceFlags
+	flags.Var(&sources, "src", "source `dir` or prefix=dir, in cascade order (repeatable)")
+	out := flags.String("o", "strata_gen.go", "output `file`")
+	pkg := flags.String("pkg", os.Getenv("GOPACKAGE"), "package `name` (default $GOPACKAGE)")
+	tokens := flags.String("tokens", "tokens", "`layer` whose custom properties become constants")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if len(sources) == 0 {
+		return errors.New("gen: at least one -src is required")
+	}
+	if *pkg == "" {
+		return errors.New("gen: -pkg is required outside go generate")
+	}
+
+	res, err := strata.Compile(sources...)
+	if err != nil {
+		return fmt.Errorf("gen: %w", err)
+	}
+
+	src, err := generateConstants(*pkg, res, *tokens)
+	if err != nil {
+		return fmt.Errorf("gen: %w", err)
+	}
+
+	return os.WriteFile(*out, src, 0o644)
+}
+
+// generateConstants returns formatted Go source declaring a Layer constant
+// for every layer in res and a Prop constant for every custom property
+// declared in tokensLayer.
+func generateConstants(pkg string, res *strata.Result, tokensLayer string) ([]byte, error) {
+	var b bytes.Buffer
+	b.WriteString("// Code generated by strata gen; DO NOT EDIT.\n\n")
+	fmt.Fprintf(&b, "package %s\n\n", pkg)
+
+	names := make(map[string]string)
+	declare := func(ident, value string) error {
+		if prev, ok := names[ident]; ok {
+			return fmt.Errorf("%q and %q both map to %s", prev, value, ident)
+		}
+		names[ident] = value
+		fmt.Fprintf(&b, "\t%s = %q\n", ident, value)
+		return nil
+	}
+
+	b.WriteString("// Layer names, in cascade order.\nconst (\n")
+	for _, l := range res.Layers {
+		if err := declare("Layer"+exportedName(l.Name), l.Name); err != nil {
+			return nil, err
+		}
+	}
+	b.WriteString(")\n")
+
+	props := res.CustomProperties(tokensLayer)
+	if len(props) > 0 {
+		fmt.Fprintf(&b, "\n// Custom properties declared in the %s layer.\nconst (\n", tokensLayer)
+		for _, prop := range props {
+			if err := declare("Prop"+exportedName(prop), prop); err != nil {
+				return nil, err
+			}
+		}
+		b.WriteString(")\n")
+	}
+
+	return format.Source(b.Bytes())
+}
+
+// exportedName converts a layer or property name to the CamelCase suffix of
+// a Go identifier, treating any non-alphanumeric character as a word break
+// (e.g., "base.elements" -> "BaseElements", "--color-primary" -> "ColorPrimary").
+func exportedName(name string) string {
+	var b strings.Builder
+	upper := true
+	for _, r := range name {
+		switch {
+		case r >= 'a' && r <= 'z':
+			if upper {
+				r -= 'a' - 'A'
+			}
+			b.WriteRune(r)
+			upper = false
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			b.WriteRune(r)
+			upper = false
+		default:
+			upper = true
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	strata "github.com/rlebel12/strata-go"
+)
+
+func TestExportedName(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		giveName string
+		want     string
+	}{
+		{name: "single", giveName: "reset", want: "Reset"},
+		{name: "dotted", giveName: "base.elements", want: "BaseElements"},
+		{name: "hyphen", giveName: "my-layer", want: "MyLayer"},
+		{name: "property", giveName: "--color-primary", want: "ColorPrimary"},
+		{name: "digits", giveName: "--space-2xl", want: "Space2xl"},
+		{name: "underscore", giveName: "_private", want: "Private"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := exportedName(tt.giveName); got != tt.want {
+				t.Errorf("exportedName(%q) = %q, want %q", tt.giveName, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGenerateConstants(t *testing.T) {
+	t.Parallel()
+
+	res, err := strata.Compile(strata.Source{FS: fstest.MapFS{
+		"reset.css":             {Data: []byte("* {}")},
+		"tokens.css":            {Data: []byte(":root { --color-primary: blue; --radius-sm: 4px; }")},
+		"base/elements/btn.css": {Data: []byte("button {}")},
+	}})
+	if err != nil {
+		t.Fatalf("Compile() error = %v", err)
+	}
+
+	got, err := generateConstants("styles", res, "tokens")
+	if err != nil {
+		t.Fatalf("generateConstants() error = %v, want nil", err)
+	}
+
+	want := `// Code generated by strata gen; DO NOT EDIT.
+
+package styles
+
+// Layer names, in cascade order.
+const (
+	LayerReset        = "reset"
+	LayerTokens       = "tokens"
+	LayerBaseElements = "base.elements"
+)
+
+// Custom properties declared in the tokens layer.
+const (
+	PropColorPrimary = "--color-primary"
+	PropRadiusSm     = "--radius-sm"
+)
+`
+	if string(got) != want {
+		t.Errorf("generateConstants() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestGenerateConstants_collision(t *testing.T) {
+	t.Parallel()
+
+	res, err := strata.Compile(strata.Source{FS: fstest.MapFS{
+		"a-b.css": {Data: []byte("x")},
+		"a/b.css": {Data: []byte("y")},
+	}, Mode: strata.LayerByFile})
+	if err != nil {
+		t.Fatalf("Compile() error = %v", err)
+	}
+
+	_, err = generateConstants("styles", res, "tokens")
+	if err == nil {
+		t.Fatal("generateConstants() error = nil, want error")
+	}
+	if !strings.Contains(err.Error(), "LayerAB") {
+		t.Errorf("generateConstants() error = %q, want error naming LayerAB", err.Error())
+	}
+}
+
+func TestRun_gen(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "css", "reset.css"), "* {}")
+	writeFile(t, filepath.Join(dir, "components", "card.css"), ".card {}")
+	out := filepath.Join(dir, "styles_gen.go")
+
+	err := run([]string{
+		"gen",
+		"-src", filepath.Join(dir, "css"),
+		"-src", "comp=" + filepath.Join(dir, "components"),
+		"-pkg", "styles",
+		"-o", out,
+	}, io.Discard)
+	if err != nil {
+		t.Fatalf("run() error = %v, want nil", err)
+	}
+
+	got, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+	for _, want := range []string{"package styles", `LayerReset    = "reset"`, `LayerCompCard = "comp.card"`} {
+		if !strings.Contains(string(got), want) {
+			t.Errorf("generated file missing %q, got:\n%s", want, got)
+		}
+	}
+}
+
+func TestRun_errors(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		giveArgs  []string
+		wantError string
+	}{
+		{name: "no_command", giveArgs: nil, wantError: "missing command"},
+		{name: "unknown_command", giveArgs: []string{"nope"}, wantError: `unknown command "nope"`},
+		{name: "gen_without_src", giveArgs: []string{"gen", "-pkg", "x"}, wantError: "-src is required"},
+		{name: "gen_empty_src", giveArgs: []string{"gen", "-src", "p="}, wantError: "empty source directory"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := run(tt.giveArgs, io.Discard)
+			if err == nil {
+				t.Fatal("run() error = nil, want error")
+			}
+			if !strings.Contains(err.Error(), tt.wantError) {
+				t.Errorf("run() error = %q, want error containing %q", err.Error(), tt.wantError)
+			}
+		})
+	}
+}
+
+// writeFile creates the file at name, and any missing parent directories.
+func writeFile(t *testing.T, name, content string) {
+	t.Helper()
+
+	if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+		t.Fatalf("MkdirAll() error = %v", err)
+	}
+	if err := os.WriteFile(name, []byte(content), 0o644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+}
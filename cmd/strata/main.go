@@ -0,0 +1,81 @@
+// Command strata builds layered CSS and generates Go code from it.
+//
+// Usage:
+//
+//	strata gen [flags]
+//	strata embed [flags]
+//
+// Each subcommand reads one or more source directories, given with -src in
+// cascade order. A source may carry a layer prefix as "prefix=dir":
+//
+//	//go:generate go run github.com/rlebel12/strata-go/cmd/strata gen -src css -src comp=components
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	strata "github.com/rlebel12/strata-go"
+)
+
+const usage = `usage: strata <command> [flags]
+
+Commands:
+  gen    write Go constants for layer names and custom properties
+  embed  write the built CSS and a Go file embedding it with its hash
+
+Run "strata <command> -h" for command flags.
+`
+
+func main() {
+	if err := run(os.Args[1:], os.Stderr); err != nil {
+		if !errors.Is(err, flag.ErrHelp) {
+			fmt.Fprintln(os.Stderr, "strata:", err)
+		}
+		os.Exit(1)
+	}
+}
+
+// run dispatches args to a subcommand, writing usage and flag errors to stderr.
+func run(args []string, stderr io.Writer) error {
+	if len(args) == 0 {
+		fmt.Fprint(stderr, usage)
+		return errors.New("missing command")
+	}
+
+	switch args[0] {
+	case "gen":
+		return runGen(args[1:], stderr)
+	case "embed":
+		return runEmbed(args[1:], stderr)
+	case "-h", "-help", "--help", "help":
+		fmt.Fprint(stderr, usage)
+		return flag.ErrHelp
+	default:
+		fmt.Fprint(stderr, usage)
+		return fmt.Errorf("unknown command %q", args[0])
+	}
+}
+
+// sourceFlags collects repeated -src flags of the form "dir" or "prefix=dir".
+type sourceFlags []strata.Source
+
+func (s *sourceFlags) String() string {
+	return ""
+}
+
+func (s *sourceFlags) Set(value string) error {
+	prefix, dir, found := strings.Cut(value, "=")
+	if !found {
+		prefix, dir = "", value
+	}
+	if dir == "" {
+		return errors.New("empty source directory")
+	}
+	*s = append(*s, strata.Source{FS: os.DirFS(dir), Prefix: prefix})
+	return nil
+}
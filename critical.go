@@ -0,0 +1,70 @@
+package strata
+
+import "strings"
+
+// Critical returns the part of the result needed to render the given HTML
+// document: the style rules whose selectors match at least one of its
+// elements, inside their original @layer, @media and other grouping
+// blocks. Inline the critical CSS in the page and load the full
+// stylesheet later.
+//
+// The returned result's Header still declares every layer, so the cascade
+// order is the same once the full stylesheet arrives. Layers without a
+// matching rule are omitted from the body.
+//
+// Matching errs on the side of keeping rules: selectors the matcher cannot
+// read, and pseudo-classes such as :hover that depend on state, are
+// assumed to match. At-rules such as @font-face and @keyframes are always
+// kept.
+//
+// For an OutputImports result, the body renders the matching rules as
+// flat layer blocks rather than importing whole files, with url()
+// references left as written in each file.
+func (r *Result) Critical(html string) *Result {
+	var elements []*htmlElement
+	parseHTML(html).walk(func(e *htmlElement) {
+		elements = append(elements, e)
+	})
+
+	return r.filter(func(selectors string) bool {
+		list, ok := parseSelectorList(selectors)
+		if !ok {
+			return true
+		}
+		for _, e := range elements {
+			if matchesAny(list, e) {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// filter returns a copy of the result keeping only the style rules whose
+// selector list keep accepts. The copy's header still declares every
+// layer of the original. Imports cannot be filtered, so an OutputImports
+// copy renders its content flat instead.
+func (r *Result) filter(keep func(selectors string) bool) *Result {
+	filtered := *r
+	filtered.Layers = nil
+	if r.output == OutputImports {
+		filtered.output = OutputFlat
+		filtered.files, filtered.unlayeredFiles = nil, nil
+	}
+	if filtered.order == nil {
+		filtered.order = uniqueNames(layerNames(r.Layers))
+	}
+
+	for _, l := range r.Layers {
+		if !l.External {
+			l.Content = filterRules(l.Content, keep)
+			if strings.TrimSpace(l.Content) == "" {
+				continue
+			}
+		}
+		filtered.Layers = append(filtered.Layers, l)
+	}
+	filtered.Unlayered = filterRules(r.Unlayered, keep)
+
+	return &filtered
+}
@@ -0,0 +1,119 @@
+package strata
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestResult_Critical(t *testing.T) {
+	t.Parallel()
+
+	testFS := fstest.MapFS{
+		"base/base.css": {Data: []byte("body { margin: 0; }\ntable { border: 0; }")},
+		"components/card/card.css": {Data: []byte(
+			".card { padding: 1rem; }\n.card:hover { color: red; }\n" +
+				"@media (width > 40rem) {\n\t.card { padding: 2rem; }\n\t.modal { inset: 0; }\n}",
+		)},
+		"components/modal/modal.css": {Data: []byte(".modal { position: fixed; }")},
+		"utilities/u.css":            {Data: []byte(".hidden { display: none; }\n@font-face { font-family: X; }")},
+	}
+	debugFS := fstest.MapFS{
+		"debug.css": {Data: []byte(".card { outline: 1px solid; }\n.debug-only {}")},
+	}
+
+	html := `<html><body><div class="card">Hi</div></body></html>`
+
+	tests := []struct {
+		name   string
+		output Output
+		want   string
+	}{
+		{
+			name:   "flat",
+			output: OutputFlat,
+			want: "@layer base, utilities, components.card, components.modal, tw;\n" +
+				"@layer base {\nbody { margin: 0; }\n}\n" +
+				"@layer utilities {\n\n@font-face { font-family: X; }\n}\n" +
+				"@layer components.card {\n.card { padding: 1rem; }\n.card:hover { color: red; }\n" +
+				"@media (width > 40rem) {\n\t.card { padding: 2rem; }\n}\n}\n" +
+				".card { outline: 1px solid; }\n",
+		},
+		{
+			name:   "nested",
+			output: OutputNested,
+			want: "@layer base, utilities, components.card, components.modal, tw;\n" +
+				"@layer base {\nbody { margin: 0; }\n}\n" +
+				"@layer utilities {\n\n@font-face { font-family: X; }\n}\n" +
+				"@layer components {\n@layer card;\n@layer card {\n.card { padding: 1rem; }\n.card:hover { color: red; }\n" +
+				"@media (width > 40rem) {\n\t.card { padding: 2rem; }\n}\n}\n}\n" +
+				".card { outline: 1px solid; }\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			res, err := Config{Output: tt.output}.Compile(
+				Source{FS: testFS, MaxDepth: 2},
+				ExternalLayer("tw"),
+				Source{FS: debugFS, Unlayered: true},
+			)
+			if err != nil {
+				t.Fatalf("Config.Compile() error = %v, want nil", err)
+			}
+			full := res.CSS()
+
+			if got := res.Critical(html).CSS(); got != tt.want {
+				t.Errorf("Result.Critical().CSS() =\n%s\nwant:\n%s", got, tt.want)
+			}
+			if res.CSS() != full {
+				t.Error("Result.Critical() modified the original result")
+			}
+		})
+	}
+}
+
+func TestResult_Critical_bundle_header(t *testing.T) {
+	t.Parallel()
+
+	res, err := CompileBundles(
+		Bundle{Name: "common", Sources: []Source{{FS: fstest.MapFS{"a.css": {Data: []byte(".a {}")}}}}},
+		Bundle{Name: "page", Sources: []Source{{FS: fstest.MapFS{"b.css": {Data: []byte(".b {}")}}}}},
+	)
+	if err != nil {
+		t.Fatalf("CompileBundles() error = %v, want nil", err)
+	}
+
+	got := res["common"].Critical(`<p class="b">`).CSS()
+	if want := "@layer a, b;\n"; got != want {
+		t.Errorf("Result.Critical().CSS() = %q, want %q", got, want)
+	}
+}
+
+func TestResult_Critical_imports(t *testing.T) {
+	t.Parallel()
+
+	res, err := Config{Output: OutputImports}.Compile(
+		Source{FS: fstest.MapFS{"a.css": {Data: []byte(".a {}\n.x {}")}}, URL: "app"},
+		Source{FS: fstest.MapFS{"b.css": {Data: []byte(".b {}\n.y {}")}}, URL: "debug", Unlayered: true},
+	)
+	if err != nil {
+		t.Fatalf("Config.Compile() error = %v, want nil", err)
+	}
+	if !strings.Contains(res.Body(), "@import") {
+		t.Fatalf("Result.Body() = %q, want imports", res.Body())
+	}
+
+	// Imports cannot be filtered, so the copies render their rules flat
+	want := "@layer a;\n@layer a {\n.a {}\n}\n.b {}\n"
+	if got := res.Critical(`<p class="a b">`).CSS(); got != want {
+		t.Errorf("Result.Critical().CSS() = %q, want %q", got, want)
+	}
+	u := &Usage{}
+	u.Add(`<p class="a b">`)
+	if got := res.Prune(u).CSS(); got != want {
+		t.Errorf("Result.Prune().CSS() = %q, want %q", got, want)
+	}
+}
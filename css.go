@@ -0,0 +1,322 @@
+package strata
+
+import "strings"
+
+// cssNode is a statement or block in a parsed stylesheet.
+//
+// Nodes record byte offsets into the source rather than copies, so callers
+// can reproduce or rewrite the original text exactly. Each node's range
+// includes the whitespace and comments that precede it.
+type cssNode struct {
+	// start and end delimit the node, including leading whitespace and
+	// comments, and the terminating ';' or '}'.
+	start, end int
+
+	// preludeStart and preludeEnd delimit the text before '{' or ';',
+	// excluding surrounding whitespace and comments.
+	preludeStart, preludeEnd int
+
+	// block reports whether the node has a { } block.
+	block bool
+
+	// bodyStart and bodyEnd delimit the block contents between the braces.
+	bodyStart, bodyEnd int
+
+	// children are the statements and blocks inside the block.
+	children []*cssNode
+}
+
+// prelude returns the node's prelude text from src.
+func (n *cssNode) prelude(src string) string {
+	return src[n.preludeStart:n.preludeEnd]
+}
+
+// atKeyword returns the lowercase at-rule name without "@" (e.g. "layer"),
+// or an empty string when the node is not an at-rule.
+func (n *cssNode) atKeyword(src string) string {
+	p := n.prelude(src)
+	if !strings.HasPrefix(p, "@") {
+		return ""
+	}
+	end := 1
+	for end < len(p) && isNameChar(p[end]) {
+		end++
+	}
+	return strings.ToLower(p[1:end])
+}
+
+// line returns the 1-based line number of the node's prelude in src.
+func (n *cssNode) line(src string) int {
+	return strings.Count(src[:n.preludeStart], "\n") + 1
+}
+
+// parseCSS parses src into a tree of statements and blocks.
+//
+// The parser is deliberately forgiving: it tracks only comments, strings,
+// brackets and braces, which is enough to split a stylesheet into rules,
+// at-rules and declarations, including nested rules. Unbalanced input never
+// fails; it simply ends the current block.
+func parseCSS(src string) []*cssNode {
+	nodes, _ := parseCSSBlock(src, 0, false)
+	return nodes
+}
+
+// parseCSSBlock parses nodes from pos until the end of src or, when nested,
+// a closing brace. It returns the nodes and the position of the closing
+// brace (or len(src)).
+func parseCSSBlock(src string, pos int, nested bool) ([]*cssNode, int) {
+	var nodes []*cssNode
+	for {
+		start := pos
+		pos = skipSpaceAndComments(src, pos)
+		if pos >= len(src) {
+			return nodes, len(src)
+		}
+		if src[pos] == '}' {
+			if nested {
+				return nodes, pos
+			}
+			// Stray closing brace at the top level: skip it
+			pos++
+			continue
+		}
+		if src[pos] == ';' {
+			// Empty statement
+			pos++
+			continue
+		}
+
+		n := &cssNode{start: start, preludeStart: pos}
+		pos = scanPrelude(src, pos)
+		n.preludeEnd = trimSpaceAndCommentsRight(src, n.preludeStart, pos)
+
+		switch {
+		case pos >= len(src):
+			n.end = len(src)
+		case src[pos] == ';':
+			n.end = pos + 1
+			pos++
+		case src[pos] == '{':
+			n.block = true
+			n.bodyStart = pos + 1
+			n.children, pos = parseCSSBlock(src, pos+1, true)
+			n.bodyEnd = pos
+			if pos < len(src) {
+				pos++ // closing brace
+			}
+			n.end = pos
+		default: // '}' ends a final declaration without a semicolon
+			n.end = pos
+		}
+		nodes = append(nodes, n)
+	}
+}
+
+// scanPrelude advances from pos to the next '{', ';' or '}' outside of
+// strings, comments and brackets, returning its position (or len(src)).
+func scanPrelude(src string, pos int) int {
+	depth := 0
+	for pos < len(src) {
+		switch c := src[pos]; {
+		case c == '/' && strings.HasPrefix(src[pos:], "/*"):
+			pos = skipComment(src, pos)
+			continue
+		case c == '"' || c == '\'':
+			pos = skipString(src, pos)
+			continue
+		case c == '\\':
+			pos += 2
+			continue
+		case c == '(' || c == '[':
+			depth++
+		case c == ')' || c == ']':
+			if depth > 0 {
+				depth--
+			}
+		case depth == 0 && (c == '{' || c == ';' || c == '}'):
+			return pos
+		}
+		pos++
+	}
+	return len(src)
+}
+
+// skipSpaceAndComments returns the position of the next character in src
+// that is neither whitespace nor part of a comment.
+func skipSpaceAndComments(src string, pos int) int {
+	for pos < len(src) {
+		switch {
+		case isSpace(src[pos]):
+			pos++
+		case strings.HasPrefix(src[pos:], "/*"):
+			pos = skipComment(src, pos)
+		default:
+			return pos
+		}
+	}
+	return pos
+}
+
+// trimSpaceAndCommentsRight returns the end of src[start:end] with trailing
+// whitespace and comments removed.
+func trimSpaceAndCommentsRight(src string, start, end int) int {
+	for end > start {
+		switch {
+		case isSpace(src[end-1]):
+			end--
+		case strings.HasSuffix(src[start:end], "*/"):
+			open := strings.LastIndex(src[start:end-2], "/*")
+			if open < 0 {
+				return end
+			}
+			end = start + open
+		default:
+			return end
+		}
+	}
+	return end
+}
+
+// skipComment returns the position just after the comment starting at pos.
+// An unterminated comment runs to the end of src.
+func skipComment(src string, pos int) int {
+	end := strings.Index(src[pos+2:], "*/")
+	if end < 0 {
+		return len(src)
+	}
+	return pos + 2 + end + 2
+}
+
+// skipString returns the position just after the quoted string starting
+// at pos. An unterminated string ends at the next newline.
+func skipString(src string, pos int) int {
+	quote := src[pos]
+	pos++
+	for pos < len(src) {
+		switch src[pos] {
+		case '\\':
+			pos += 2
+			continue
+		case quote:
+			return pos + 1
+		case '\n':
+			return pos
+		}
+		pos++
+	}
+	return len(src)
+}
+
+// isSpace reports whether c is CSS whitespace.
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f'
+}
+
+// isNameChar reports whether c can appear in a CSS identifier.
+func isNameChar(c byte) bool {
+	return c == '-' || c == '_' || c >= 0x80 ||
+		(c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
+
+// declaredProperties returns the custom properties declared anywhere in
+// src, in source order, including repeats.
+func declaredProperties(src string) []string {
+	var props []string
+	var walk func(nodes []*cssNode)
+	walk = func(nodes []*cssNode) {
+		for _, n := range nodes {
+			if n.block {
+				walk(n.children)
+				continue
+			}
+			p := n.prelude(src)
+			colon := strings.IndexByte(p, ':')
+			if !strings.HasPrefix(p, "--") || colon < 0 {
+				continue
+			}
+			props = append(props, strings.TrimSpace(p[:colon]))
+		}
+	}
+	walk(parseCSS(src))
+	return props
+}
+
+// splitSelectorList splits a selector list on its top-level commas,
+// trimming whitespace from each selector.
+func splitSelectorList(s string) []string {
+	var parts []string
+	depth, start := 0, 0
+	for pos := 0; pos < len(s); {
+		switch s[pos] {
+		case '"', '\'':
+			pos = skipString(s, pos)
+			continue
+		case '\\':
+			pos += 2
+			continue
+		case '(', '[':
+			depth++
+		case ')', ']':
+			depth--
+		case ',':
+			if depth == 0 {
+				parts = append(parts, strings.TrimSpace(s[start:pos]))
+				start = pos + 1
+			}
+		}
+		pos++
+	}
+	return append(parts, strings.TrimSpace(s[start:]))
+}
+
+// groupingRules are the at-rules whose blocks hold style rules.
+var groupingRules = map[string]bool{
+	"media": true, "supports": true, "layer": true, "scope": true,
+	"container": true, "document": true, "-moz-document": true,
+	"starting-style": true,
+}
+
+// filterRules returns src without the style rules whose selector list
+// keep rejects. Grouping at-rules such as @media are kept while any rule
+// inside them is. Rules nested inside a kept rule, other at-rules such as
+// @font-face and @keyframes, and statements are kept as written.
+func filterRules(src string, keep func(selectors string) bool) string {
+	var filter func(nodes []*cssNode) (string, bool)
+	filter = func(nodes []*cssNode) (string, bool) {
+		var out strings.Builder
+		kept := false
+		for _, n := range nodes {
+			kw := n.atKeyword(src)
+			switch {
+			case !n.block:
+			case kw == "" && !keep(n.prelude(src)):
+				continue
+			case groupingRules[kw]:
+				inner, ok := filter(n.children)
+				if !ok {
+					continue
+				}
+				// Keep the whitespace before the closing brace
+				tail := n.bodyStart
+				if len(n.children) > 0 {
+					tail = n.children[len(n.children)-1].end
+				}
+				out.WriteString(src[n.start:n.bodyStart])
+				out.WriteString(inner)
+				out.WriteString(src[tail:n.end])
+				kept = true
+				continue
+			}
+			out.WriteString(src[n.start:n.end])
+			kept = true
+		}
+		return out.String(), kept
+	}
+
+	nodes := parseCSS(src)
+	out, kept := filter(nodes)
+	if !kept {
+		return ""
+	}
+	return out + src[nodes[len(nodes)-1].end:]
+}
@@ -0,0 +1,196 @@
+package strata
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// describeCSS renders parsed nodes as a compact outline for comparison:
+// statements as "prelude;" and blocks as "prelude{children}".
+func describeCSS(src string, nodes []*cssNode) string {
+	var b strings.Builder
+	for _, n := range nodes {
+		b.WriteString(n.prelude(src))
+		if n.block {
+			b.WriteString("{")
+			b.WriteString(describeCSS(src, n.children))
+			b.WriteString("}")
+		} else {
+			b.WriteString(";")
+		}
+	}
+	return b.String()
+}
+
+func TestParseCSS(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		giveCSS  string
+		wantTree string
+	}{
+		{
+			name:     "rule_with_declarations",
+			giveCSS:  "a { color: red; margin: 0 }",
+			wantTree: "a{color: red;margin: 0;}",
+		},
+		{
+			name:     "at_rule_statement",
+			giveCSS:  "@layer a, b;\n@import url(x.css);",
+			wantTree: "@layer a, b;@import url(x.css);",
+		},
+		{
+			name:     "nested_rules",
+			giveCSS:  ".card {\n\tpadding: 0;\n\t&:hover { color: red; }\n\t.title { margin: 0; }\n}",
+			wantTree: ".card{padding: 0;&:hover{color: red;}.title{margin: 0;}}",
+		},
+		{
+			name:     "comments_are_skipped",
+			giveCSS:  "/* a { } */ b /* c */ { /* ; */ d: e; }",
+			wantTree: "b{d: e;}",
+		},
+		{
+			name:     "strings_hide_delimiters",
+			giveCSS:  `a::before { content: "};{"; }`,
+			wantTree: `a::before{content: "};{";}`,
+		},
+		{
+			name:     "parens_hide_semicolons",
+			giveCSS:  "a { background: url(data:image/png;base64,AAA); }",
+			wantTree: "a{background: url(data:image/png;base64,AAA);}",
+		},
+		{
+			name:     "unterminated_block",
+			giveCSS:  "a { color: red;",
+			wantTree: "a{color: red;}",
+		},
+		{
+			name:     "stray_closing_brace",
+			giveCSS:  "} a { }",
+			wantTree: "a{}",
+		},
+		{
+			name:     "empty",
+			giveCSS:  "  \n",
+			wantTree: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got := describeCSS(tt.giveCSS, parseCSS(tt.giveCSS))
+			if got != tt.wantTree {
+				t.Errorf("parseCSS(%q) = %q, want %q", tt.giveCSS, got, tt.wantTree)
+			}
+		})
+	}
+}
+
+func TestParseCSS_offsets_cover_source(t *testing.T) {
+	t.Parallel()
+
+	src := "/* head */\na { b: c; }\n\n@media print {\n  d { e: f }\n}\n"
+	nodes := parseCSS(src)
+
+	// Consecutive top-level nodes cover the source without gaps
+	var got strings.Builder
+	for _, n := range nodes {
+		got.WriteString(src[n.start:n.end])
+	}
+	want := strings.TrimRight(src, "\n")
+	if got.String() != want {
+		t.Errorf("parseCSS() node ranges = %q, want %q", got.String(), want)
+	}
+
+	if line := nodes[1].line(src); line != 4 {
+		t.Errorf("cssNode.line() = %d, want 4", line)
+	}
+	if kw := nodes[1].atKeyword(src); kw != "media" {
+		t.Errorf("cssNode.atKeyword() = %q, want %q", kw, "media")
+	}
+	if kw := nodes[0].atKeyword(src); kw != "" {
+		t.Errorf("cssNode.atKeyword() = %q, want empty", kw)
+	}
+}
+
+func TestParseCSS_at_keyword_case(t *testing.T) {
+	t.Parallel()
+
+	src := "@LAYER x { }"
+	got := []string{}
+	for _, n := range parseCSS(src) {
+		got = append(got, n.atKeyword(src))
+	}
+	if want := []string{"layer"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("cssNode.atKeyword() = %v, want %v", got, want)
+	}
+}
+
+func TestSplitSelectorList(t *testing.T) {
+	t.Parallel()
+
+	got := splitSelectorList(` a, :is(b, c) > d ,[data-x="1,2"], e\,f `)
+	want := []string{"a", ":is(b, c) > d", `[data-x="1,2"]`, `e\,f`}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("splitSelectorList() = %q, want %q", got, want)
+	}
+}
+
+func TestFilterRules(t *testing.T) {
+	t.Parallel()
+
+	keep := func(selectors string) bool {
+		return strings.Contains(selectors, ".keep")
+	}
+
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{
+			name: "rules",
+			src:  ".keep { a: 1; }\n.drop { b: 2; }\n.x, .keep { c: 3; }\n",
+			want: ".keep { a: 1; }\n.x, .keep { c: 3; }\n",
+		},
+		{
+			name: "grouping rules",
+			src:  "@media (width > 1px) {\n\t.drop {}\n\t.keep {}\n}\n@supports (x: y) {\n\t.drop {}\n}\n",
+			want: "@media (width > 1px) {\n\t.keep {}\n}\n",
+		},
+		{
+			name: "other at-rules and statements",
+			src:  "@import url(x.css);\n@font-face { font-family: X; }\n@keyframes spin { from {} to {} }\n.drop {}\n",
+			want: "@import url(x.css);\n@font-face { font-family: X; }\n@keyframes spin { from {} to {} }\n",
+		},
+		{
+			name: "nested rules",
+			src:  ".keep {\n\t& .drop { a: 1; }\n}\n",
+			want: ".keep {\n\t& .drop { a: 1; }\n}\n",
+		},
+		{
+			name: "nothing kept",
+			src:  ".drop {}\n@media print { .drop {} }\n",
+			want: "",
+		},
+		{
+			name: "empty",
+			src:  "",
+			want: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := filterRules(tt.src, keep); got != tt.want {
+				t.Errorf("filterRules() =\n%q\nwant:\n%q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,142 @@
+package strata
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// FileLayerPolicy controls how Compile treats @layer rules inside source files.
+//
+// A file under components/ containing "@layer state { ... }" creates the
+// layer components.state, whose order the top-level header cannot see.
+// The policy keeps such ordering centrally controlled when needed.
+type FileLayerPolicy int
+
+const (
+	// AllowFileLayers reports file-declared layers in Result.FileLayers.
+	AllowFileLayers FileLayerPolicy = iota
+
+	// WarnFileLayers also adds a warning to Result.Warnings for each
+	// file-declared layer.
+	WarnFileLayers
+
+	// RejectFileLayers makes Compile fail on the first file-declared layer.
+	RejectFileLayers
+)
+
+// FileLayer is a layer declared by an @layer rule inside a source file.
+type FileLayer struct {
+	// Name is the fully-qualified layer name, including the layer of the
+	// declaring file (e.g., components.state).
+	Name string
+
+	// Path is the declaring file's path within its Source.FS.
+	Path string
+
+	// Line is the 1-based line of the declaring @layer rule.
+	Line int
+}
+
+// String formats the declaration as "path:line: @layer name".
+func (f FileLayer) String() string {
+	return fmt.Sprintf("%s:%d: @layer %s", f.Path, f.Line, f.Name)
+}
+
+// checkFileLayers records the layers declared inside a file's content and
+// applies the configured policy. Each name is recorded once. Names in
+// childOrder, which an index file lists to order its directory's layers,
+// are not file-declared layers and are skipped.
+func (c *compiler) checkFileLayers(filePath, layerName, content string, childOrder []string) error {
+	for _, fl := range declaredLayers(content, layerName) {
+		if slices.Contains(childOrder, fl.Name) {
+			continue // an index file ordering its directory's layers
+		}
+		fl.Path = filePath
+
+		switch c.config.FileLayers {
+		case RejectFileLayers:
+			return fmt.Errorf("%s: file declares layer", fl)
+		case WarnFileLayers:
+			c.warnings = append(c.warnings, fmt.Sprintf("%s: file declares layer", fl))
+		}
+
+		if !c.hasFileLayer(fl.Name) {
+			c.fileLayers = append(c.fileLayers, fl)
+		}
+	}
+	return nil
+}
+
+// hasFileLayer reports whether a file-declared layer name is already recorded.
+func (c *compiler) hasFileLayer(name string) bool {
+	for _, fl := range c.fileLayers {
+		if fl.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// declaredLayers returns the layers declared by @layer rules in src, in
+// source order, qualified by parent. Anonymous layers cannot be ordered or
+// referenced, so they and their contents are not reported.
+func declaredLayers(src, parent string) []FileLayer {
+	var found []FileLayer
+	var walk func(nodes []*cssNode, parent string)
+	walk = func(nodes []*cssNode, parent string) {
+		for _, n := range nodes {
+			if n.atKeyword(src) != "layer" {
+				if n.block {
+					walk(n.children, parent)
+				}
+				continue
+			}
+
+			names := strings.TrimSpace(n.prelude(src)[len("@layer"):])
+			if names == "" {
+				continue // anonymous layer
+			}
+			for _, name := range strings.Split(names, ",") {
+				found = append(found, FileLayer{
+					Name: qualifyLayerName(parent, strings.TrimSpace(name)),
+					Line: n.line(src),
+				})
+			}
+			if n.block {
+				walk(n.children, qualifyLayerName(parent, names))
+			}
+		}
+	}
+	walk(parseCSS(src), parent)
+	return found
+}
+
+// qualifyLayerName joins a parent layer name and a child name with a dot.
+// An empty parent leaves the child name unchanged.
+func qualifyLayerName(parent, name string) string {
+	if parent == "" {
+		return name
+	}
+	return parent + "." + name
+}
+
+// indexChildOrder returns the names listed by an index file's top-level
+// @layer statements, qualified by the file's layer, that name layers the
+// source produces. Those order the directory's child layers; other names
+// are left to order layers declared within the file.
+func indexChildOrder(src, parent string, sourceLayers map[string]bool) []string {
+	var names []string
+	for _, n := range parseCSS(src) {
+		if n.block || n.atKeyword(src) != "layer" {
+			continue
+		}
+		for _, name := range strings.Split(n.prelude(src)[len("@layer"):], ",") {
+			name = qualifyLayerName(parent, strings.TrimSpace(name))
+			if sourceLayers[name] {
+				names = append(names, name)
+			}
+		}
+	}
+	return names
+}
@@ -0,0 +1,147 @@
+package strata
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestDeclaredLayers(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name       string
+		giveCSS    string
+		giveParent string
+		wantNames  []string
+	}{
+		{
+			name:       "none",
+			giveCSS:    "a { color: red; }",
+			giveParent: "components",
+			wantNames:  nil,
+		},
+		{
+			name:       "block",
+			giveCSS:    "@layer state { .x {} }",
+			giveParent: "components",
+			wantNames:  []string{"components.state"},
+		},
+		{
+			name:       "statement",
+			giveCSS:    "@layer a, b;",
+			giveParent: "components",
+			wantNames:  []string{"components.a", "components.b"},
+		},
+		{
+			name:       "nested_blocks",
+			giveCSS:    "@layer a { @layer b { } @layer c.d; }",
+			giveParent: "base",
+			wantNames:  []string{"base.a", "base.a.b", "base.a.c.d"},
+		},
+		{
+			name:       "inside_other_at_rules",
+			giveCSS:    "@media print { @layer print { } }",
+			giveParent: "base",
+			wantNames:  []string{"base.print"},
+		},
+		{
+			name:       "anonymous_skipped",
+			giveCSS:    "@layer { @layer hidden { } }",
+			giveParent: "base",
+			wantNames:  nil,
+		},
+		{
+			name:       "unlayered_parent",
+			giveCSS:    "@layer top { }",
+			giveParent: "",
+			wantNames:  []string{"top"},
+		},
+		{
+			name:       "ignores_comments",
+			giveCSS:    "/* @layer fake; */ a { content: \"@layer nope;\"; }",
+			giveParent: "base",
+			wantNames:  nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var got []string
+			for _, fl := range declaredLayers(tt.giveCSS, tt.giveParent) {
+				got = append(got, fl.Name)
+			}
+			if !reflect.DeepEqual(got, tt.wantNames) {
+				t.Errorf("declaredLayers(%q, %q) = %v, want %v",
+					tt.giveCSS, tt.giveParent, got, tt.wantNames)
+			}
+		})
+	}
+}
+
+func TestCompile_file_layers(t *testing.T) {
+	t.Parallel()
+
+	testFS := fstest.MapFS{
+		"components/button.css": {Data: []byte("a {}\n@layer state {\n.x {}\n}\n")},
+		"components/card.css":   {Data: []byte("@layer state;\n")},
+		"reset.css":             {Data: []byte("* {}")},
+	}
+
+	tests := []struct {
+		name         string
+		givePolicy   FileLayerPolicy
+		wantErr      bool
+		wantWarnings int
+	}{
+		{
+			name:         "allow",
+			givePolicy:   AllowFileLayers,
+			wantWarnings: 0,
+		},
+		{
+			name:         "warn",
+			givePolicy:   WarnFileLayers,
+			wantWarnings: 2,
+		},
+		{
+			name:       "reject",
+			givePolicy: RejectFileLayers,
+			wantErr:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := Config{FileLayers: tt.givePolicy}.Compile(Source{FS: testFS})
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("Config.Compile() error = nil, want error")
+				}
+				if !strings.Contains(err.Error(), "components/button.css:2") {
+					t.Errorf("Config.Compile() error = %q, want error containing path and line", err.Error())
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Config.Compile() error = %v, want nil", err)
+			}
+
+			wantFileLayers := []FileLayer{
+				{Name: "components.state", Path: "components/button.css", Line: 2},
+			}
+			if !reflect.DeepEqual(got.FileLayers, wantFileLayers) {
+				t.Errorf("Result.FileLayers = %v, want %v", got.FileLayers, wantFileLayers)
+			}
+
+			if len(got.Warnings) != tt.wantWarnings {
+				t.Errorf("Result.Warnings = %v, want %d warnings", got.Warnings, tt.wantWarnings)
+			}
+		})
+	}
+}
@@ -0,0 +1,123 @@
+package strata
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Handler returns an http.Handler that serves each source's files under
+// its Source.URL, for use with OutputImports during development. Source
+// files are processed as Build processes them, so templates, tokens,
+// transformers and scoping still apply; other files, such as images and
+// fonts, are served as-is. Files are read on every request, so edits
+// show on reload.
+//
+// Each source with a filesystem needs its own Source.URL, not nested in
+// another's, so that every file has one URL; Handler returns an error
+// otherwise. Mount the handler at Config.BaseURL:
+//
+//	cfg := strata.Config{Output: strata.OutputImports, BaseURL: "/css"}
+//	h, err := cfg.Handler(sources...)
+//	http.Handle("/css/", http.StripPrefix("/css", h))
+func (c Config) Handler(sources ...Source) (http.Handler, error) {
+	if err := checkSourceURLs(sources); err != nil {
+		return nil, err
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for _, s := range sources {
+			filePath, ok := sourceFilePath(s, r.URL.Path)
+			if !ok {
+				continue
+			}
+			info, err := fs.Stat(s.FS, filePath)
+			if err != nil || info.IsDir() {
+				continue
+			}
+
+			if sourceExtension(filePath) == "" {
+				http.ServeFileFS(w, r, s.FS, filePath)
+				return
+			}
+
+			content, err := c.serveFile(s, filePath)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			w.Header().Set("Content-Type", "text/css; charset=utf-8")
+			w.Header().Set("Cache-Control", "no-cache")
+			http.ServeContent(w, r, filePath, time.Time{}, bytes.NewReader(content))
+			return
+		}
+		http.NotFound(w, r)
+	}), nil
+}
+
+// checkSourceURLs reports an error if two sources with filesystems share
+// a Source.URL or one's URL lies within the other's, which would give two
+// files the same URL.
+func checkSourceURLs(sources []Source) error {
+	var prefixes []string
+	for _, s := range sources {
+		if s.FS == nil {
+			continue
+		}
+		prefix := strings.Trim(s.URL, "/")
+		for _, other := range prefixes {
+			if urlWithin(prefix, other) || urlWithin(other, prefix) {
+				return fmt.Errorf("source URL %q overlaps %q; give each source its own URL", s.URL, other)
+			}
+		}
+		prefixes = append(prefixes, prefix)
+	}
+	return nil
+}
+
+// urlWithin reports whether the URL path p equals or lies under prefix.
+func urlWithin(p, prefix string) bool {
+	return prefix == "" || p == prefix || strings.HasPrefix(p, prefix+"/")
+}
+
+// sourceFilePath maps a request path to a file path within the source,
+// reporting false if the path is outside the source's URL.
+func sourceFilePath(s Source, urlPath string) (string, bool) {
+	if s.FS == nil {
+		return "", false
+	}
+
+	filePath := strings.TrimPrefix(urlPath, "/")
+	if prefix := strings.Trim(s.URL, "/"); prefix != "" {
+		rest, ok := strings.CutPrefix(filePath, prefix+"/")
+		if !ok {
+			return "", false
+		}
+		filePath = rest
+	}
+	return filePath, fs.ValidPath(filePath)
+}
+
+// serveFile processes a single source file as addSource would, wrapping
+// it in its own @scope when the source sets ScopeAttribute.
+func (c Config) serveFile(s Source, filePath string) ([]byte, error) {
+	filePaths, err := s.files()
+	if err != nil {
+		return nil, err
+	}
+	comp := &compiler{config: c, sourceLayers: s.layerTree(filePaths)}
+	layerName, content, err := comp.processFile(s, filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.ScopeAttribute != "" && layerName != "" {
+		l := &layer{name: layerName, content: bytes.NewBuffer(content)}
+		wrapInScope(l, s.ScopeAttribute)
+		content = l.content.Bytes()
+	}
+	return content, nil
+}
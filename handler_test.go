@@ -0,0 +1,196 @@
+package strata
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestConfig_Build_imports(t *testing.T) {
+	t.Parallel()
+
+	stylesFS := fstest.MapFS{
+		"reset.css":          {Data: []byte("* { margin: 0; }")},
+		"base/body.css":      {Data: []byte("body {}")},
+		"base/_layer.css":    {Data: []byte("html {}")},
+		"tokens.tokens.json": {Data: []byte(`{"color": {"$value": "#000"}}`)},
+	}
+	debugFS := fstest.MapFS{
+		"debug.css": {Data: []byte("* { outline: 1px solid; }")},
+	}
+
+	got, err := Config{Output: OutputImports, BaseURL: "/css"}.Build(
+		Source{FS: stylesFS, URL: "styles"},
+		ExternalLayer("tw"),
+		Source{FS: debugFS, URL: "debug", Unlayered: true},
+	)
+	if err != nil {
+		t.Fatalf("Config.Build() error = %v, want nil", err)
+	}
+
+	want := "@layer base, reset, tokens, tw;\n" +
+		`@import url("/css/styles/base/_layer.css") layer(base);` + "\n" +
+		`@import url("/css/styles/base/body.css") layer(base);` + "\n" +
+		`@import url("/css/styles/reset.css") layer(reset);` + "\n" +
+		`@import url("/css/styles/tokens.tokens.json") layer(tokens);` + "\n" +
+		`@import url("/css/debug/debug.css");` + "\n"
+	if got != want {
+		t.Errorf("Config.Build() =\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestConfig_Handler(t *testing.T) {
+	t.Parallel()
+
+	stylesFS := fstest.MapFS{
+		"card/card.css":        {Data: []byte(".title { background: url(bg.png); }")},
+		"card/bg.png":          {Data: []byte("png")},
+		"tokens.tokens.json":   {Data: []byte(`{"color": {"$value": "#000"}}`)},
+		"theme/theme.css.tmpl": {Data: []byte(`body { color: {{ .Color }}; }`)},
+	}
+	cfg := Config{Output: OutputImports, RewriteURLs: true, TemplateData: map[string]string{"Color": "red"}}
+	vendorFS := fstest.MapFS{
+		"card/card.css": {Data: []byte(".vendor {}")},
+	}
+	handler, err := cfg.Handler(
+		ExternalLayer("tw"),
+		Source{FS: stylesFS, URL: "styles", ScopeClasses: true, ScopeAttribute: "data-c"},
+		Source{FS: vendorFS, URL: "/vendor/"},
+	)
+	if err != nil {
+		t.Fatalf("Config.Handler() error = %v, want nil", err)
+	}
+
+	tests := []struct {
+		name        string
+		path        string
+		wantStatus  int
+		wantType    string
+		wantContent string
+	}{
+		{
+			name:       "css processed",
+			path:       "/styles/card/card.css",
+			wantStatus: http.StatusOK,
+			wantType:   "text/css; charset=utf-8",
+			wantContent: `@scope ([data-c="card"]) {` + "\n" +
+				"." + scopedClassName("card", "title") + " { background: url(bg.png); }}\n",
+		},
+		{
+			name:        "tokens converted",
+			path:        "/styles/tokens.tokens.json",
+			wantStatus:  http.StatusOK,
+			wantType:    "text/css; charset=utf-8",
+			wantContent: "@scope ([data-c=\"tokens\"]) {\n:root {\n\t--color: #000;\n}\n}\n",
+		},
+		{
+			name:        "template executed",
+			path:        "/styles/theme/theme.css.tmpl",
+			wantStatus:  http.StatusOK,
+			wantContent: "@scope ([data-c=\"theme\"]) {\nbody { color: red; }}\n",
+		},
+		{
+			name:        "asset served as-is",
+			path:        "/styles/card/bg.png",
+			wantStatus:  http.StatusOK,
+			wantType:    "image/png",
+			wantContent: "png",
+		},
+		{
+			name:        "second source",
+			path:        "/vendor/card/card.css",
+			wantStatus:  http.StatusOK,
+			wantContent: ".vendor {}",
+		},
+		{name: "outside source URL", path: "/card/card.css", wantStatus: http.StatusNotFound},
+		{name: "missing file", path: "/styles/card/missing.css", wantStatus: http.StatusNotFound},
+		{name: "directory", path: "/styles/card", wantStatus: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			res := rec.Result()
+			if res.StatusCode != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", res.StatusCode, tt.wantStatus)
+			}
+			if tt.wantType != "" && res.Header.Get("Content-Type") != tt.wantType {
+				t.Errorf("Content-Type = %q, want %q", res.Header.Get("Content-Type"), tt.wantType)
+			}
+			if tt.wantContent != "" {
+				body, _ := io.ReadAll(res.Body)
+				if string(body) != tt.wantContent {
+					t.Errorf("body = %q, want %q", body, tt.wantContent)
+				}
+			}
+		})
+	}
+}
+
+func TestConfig_Handler_error(t *testing.T) {
+	t.Parallel()
+
+	testFS := fstest.MapFS{
+		"broken.css.tmpl": {Data: []byte("{{ .Missing")},
+	}
+
+	handler, err := Config{}.Handler(Source{FS: testFS})
+	if err != nil {
+		t.Fatalf("Config.Handler() error = %v, want nil", err)
+	}
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/broken.css.tmpl", nil))
+
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusInternalServerError)
+	}
+	if !strings.Contains(rec.Body.String(), "broken.css.tmpl") {
+		t.Errorf("body = %q, want error naming the file", rec.Body.String())
+	}
+}
+
+func TestConfig_overlapping_source_URLs(t *testing.T) {
+	t.Parallel()
+
+	aFS := fstest.MapFS{"index.css": {Data: []byte("a")}}
+	bFS := fstest.MapFS{"index.css": {Data: []byte("b")}}
+
+	tests := []struct {
+		name    string
+		sources []Source
+		wantErr bool
+	}{
+		{name: "same_empty_url", sources: []Source{{FS: aFS}, {FS: bFS}}, wantErr: true},
+		{name: "same_url", sources: []Source{{FS: aFS, URL: "a"}, {FS: bFS, URL: "/a/"}}, wantErr: true},
+		{name: "nested_url", sources: []Source{{FS: aFS, URL: "a"}, {FS: bFS, URL: "a/b"}}, wantErr: true},
+		{name: "root_and_url", sources: []Source{{FS: aFS}, {FS: bFS, URL: "b"}}, wantErr: true},
+		{name: "distinct_urls", sources: []Source{{FS: aFS, URL: "a"}, {FS: bFS, URL: "ab"}}},
+		{name: "external_layer", sources: []Source{{FS: aFS}, ExternalLayer("tw")}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			cfg := Config{Output: OutputImports}
+			if _, err := cfg.Build(tt.sources...); (err != nil) != tt.wantErr {
+				t.Errorf("Config.Build() error = %v, want error %v", err, tt.wantErr)
+			}
+			if _, err := cfg.Handler(tt.sources...); (err != nil) != tt.wantErr {
+				t.Errorf("Config.Handler() error = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	// Bundled output does not import files by URL
+	if _, err := Build(Source{FS: aFS, Prefix: "a"}, Source{FS: bFS, Prefix: "b"}); err != nil {
+		t.Errorf("Build() error = %v, want nil", err)
+	}
+}
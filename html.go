@@ -0,0 +1,255 @@
+package strata
+
+import "strings"
+
+// htmlElement is an element in a parsed HTML document.
+type htmlElement struct {
+	// tag is the lowercase tag name, or empty for the document root.
+	tag string
+
+	// attrs maps lowercase attribute names to their values.
+	attrs map[string]string
+
+	parent   *htmlElement
+	children []*htmlElement
+
+	// index is the element's position among its parent's children.
+	index int
+}
+
+// classes returns the element's class names.
+func (e *htmlElement) classes() []string {
+	return strings.Fields(e.attrs["class"])
+}
+
+// hasClass reports whether the element has the class name.
+func (e *htmlElement) hasClass(class string) bool {
+	for _, c := range e.classes() {
+		if c == class {
+			return true
+		}
+	}
+	return false
+}
+
+// prev returns the element's previous sibling, or nil.
+func (e *htmlElement) prev() *htmlElement {
+	if e.parent == nil || e.index == 0 {
+		return nil
+	}
+	return e.parent.children[e.index-1]
+}
+
+// isElement reports whether e is an element rather than the document root.
+func (e *htmlElement) isElement() bool {
+	return e != nil && e.tag != ""
+}
+
+// walk calls fn for the element and its descendants in document order.
+func (e *htmlElement) walk(fn func(*htmlElement)) {
+	if e.isElement() {
+		fn(e)
+	}
+	for _, c := range e.children {
+		c.walk(fn)
+	}
+}
+
+// voidElements never have content or an end tag.
+var voidElements = map[string]bool{
+	"area": true, "base": true, "br": true, "col": true, "embed": true,
+	"hr": true, "img": true, "input": true, "link": true, "meta": true,
+	"source": true, "track": true, "wbr": true,
+}
+
+// rawTextElements contain text that is not parsed as markup.
+var rawTextElements = map[string]bool{
+	"script": true, "style": true, "textarea": true, "title": true,
+}
+
+// impliedEnds maps a tag to the open elements its start tag closes, such
+// as a <li> closing the previous <li>.
+var impliedEnds = map[string][]string{
+	"li":     {"li"},
+	"p":      {"p"},
+	"dt":     {"dt", "dd"},
+	"dd":     {"dt", "dd"},
+	"tr":     {"tr", "td", "th"},
+	"td":     {"td", "th"},
+	"th":     {"td", "th"},
+	"option": {"option"},
+}
+
+// parseHTML parses src into an element tree rooted at a document node.
+//
+// Like parseCSS, the parser is forgiving rather than conforming: it knows
+// void and raw text elements and a few implied end tags, and ignores end
+// tags without a matching open element. Text content is discarded.
+func parseHTML(src string) *htmlElement {
+	root := &htmlElement{}
+	stack := []*htmlElement{root}
+
+	for pos := 0; pos < len(src); {
+		lt := strings.IndexByte(src[pos:], '<')
+		if lt < 0 {
+			break
+		}
+		pos += lt
+		rest := src[pos:]
+
+		switch {
+		case strings.HasPrefix(rest, "<!--"):
+			end := strings.Index(rest[4:], "-->")
+			if end < 0 {
+				return root
+			}
+			pos += 4 + end + 3
+
+		case strings.HasPrefix(rest, "<!") || strings.HasPrefix(rest, "<?"):
+			pos = skipTag(src, pos)
+
+		case strings.HasPrefix(rest, "</"):
+			name, _ := scanTagName(src, pos+2)
+			pos = skipTag(src, pos)
+			for i := len(stack) - 1; i > 0; i-- {
+				if stack[i].tag == name {
+					stack = stack[:i]
+					break
+				}
+			}
+
+		case len(rest) > 1 && isASCIILetter(rest[1]):
+			el, end, selfClosing := parseStartTag(src, pos+1)
+			pos = end
+
+			for _, closes := range impliedEnds[el.tag] {
+				if top := stack[len(stack)-1]; top.tag == closes {
+					stack = stack[:len(stack)-1]
+					break
+				}
+			}
+
+			parent := stack[len(stack)-1]
+			el.parent = parent
+			el.index = len(parent.children)
+			parent.children = append(parent.children, el)
+
+			switch {
+			case voidElements[el.tag] || selfClosing:
+			case rawTextElements[el.tag]:
+				end := indexFold(src[pos:], "</"+el.tag)
+				if end < 0 {
+					return root
+				}
+				pos = skipTag(src, pos+end)
+			default:
+				stack = append(stack, el)
+			}
+
+		default:
+			pos++ // a literal '<' in text
+		}
+	}
+	return root
+}
+
+// parseStartTag parses the tag name and attributes starting at pos, just
+// after '<'. It returns the element, the position after '>', and whether
+// the tag ended with "/>".
+func parseStartTag(src string, pos int) (*htmlElement, int, bool) {
+	el := &htmlElement{attrs: make(map[string]string)}
+	el.tag, pos = scanTagName(src, pos)
+
+	for pos < len(src) {
+		for pos < len(src) && (isSpace(src[pos]) || src[pos] == '/') {
+			if src[pos] == '/' && pos+1 < len(src) && src[pos+1] == '>' {
+				return el, pos + 2, true
+			}
+			pos++
+		}
+		if pos >= len(src) {
+			break
+		}
+		if src[pos] == '>' {
+			return el, pos + 1, false
+		}
+
+		nameStart := pos
+		for pos < len(src) && !isSpace(src[pos]) && !strings.ContainsRune("=>/", rune(src[pos])) {
+			pos++
+		}
+		name := strings.ToLower(src[nameStart:pos])
+		if name == "" {
+			pos++ // a stray '=' or quote
+			continue
+		}
+
+		for pos < len(src) && isSpace(src[pos]) {
+			pos++
+		}
+		var value string
+		if pos < len(src) && src[pos] == '=' {
+			pos++
+			for pos < len(src) && isSpace(src[pos]) {
+				pos++
+			}
+			value, pos = scanAttrValue(src, pos)
+		}
+		if _, exists := el.attrs[name]; !exists {
+			el.attrs[name] = value
+		}
+	}
+	return el, len(src), false
+}
+
+// scanAttrValue returns the quoted or unquoted attribute value at pos and
+// the position after it.
+func scanAttrValue(src string, pos int) (string, int) {
+	if pos < len(src) && (src[pos] == '"' || src[pos] == '\'') {
+		end := strings.IndexByte(src[pos+1:], src[pos])
+		if end < 0 {
+			return src[pos+1:], len(src)
+		}
+		return src[pos+1 : pos+1+end], pos + 1 + end + 1
+	}
+	start := pos
+	for pos < len(src) && !isSpace(src[pos]) && src[pos] != '>' {
+		pos++
+	}
+	return src[start:pos], pos
+}
+
+// scanTagName returns the lowercase tag name at pos and the position
+// after it.
+func scanTagName(src string, pos int) (string, int) {
+	start := pos
+	for pos < len(src) && !isSpace(src[pos]) && src[pos] != '>' && src[pos] != '/' {
+		pos++
+	}
+	return strings.ToLower(src[start:pos]), pos
+}
+
+// skipTag returns the position just after the '>' closing the tag at pos.
+func skipTag(src string, pos int) int {
+	end := strings.IndexByte(src[pos:], '>')
+	if end < 0 {
+		return len(src)
+	}
+	return pos + end + 1
+}
+
+// indexFold returns the index of the first ASCII case-insensitive match
+// of substr in s, or -1.
+func indexFold(s, substr string) int {
+	for i := 0; i+len(substr) <= len(s); i++ {
+		if strings.EqualFold(s[i:i+len(substr)], substr) {
+			return i
+		}
+	}
+	return -1
+}
+
+// isASCIILetter reports whether c is an ASCII letter.
+func isASCIILetter(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
@@ -0,0 +1,84 @@
+package strata
+
+import (
+	"strings"
+	"testing"
+)
+
+// describeHTML renders the element tree as "tag#id.class(children)" for
+// comparison in tests.
+func describeHTML(e *htmlElement) string {
+	var b strings.Builder
+	for i, c := range e.children {
+		if i > 0 {
+			b.WriteByte(' ')
+		}
+		b.WriteString(c.tag)
+		if id := c.attrs["id"]; id != "" {
+			b.WriteString("#" + id)
+		}
+		for _, class := range c.classes() {
+			b.WriteString("." + class)
+		}
+		if len(c.children) > 0 {
+			b.WriteString("(" + describeHTML(c) + ")")
+		}
+	}
+	return b.String()
+}
+
+func TestParseHTML(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		html string
+		want string
+	}{
+		{
+			name: "nested elements",
+			html: `<!DOCTYPE html><html><body><div id="app" class="card  wide"><p>Hi</p></div></body></html>`,
+			want: "html(body(div#app.card.wide(p)))",
+		},
+		{
+			name: "void and self-closing elements",
+			html: `<div><img src=a.png><br/><input type="text"><svg><path d="M0"/></svg></div>`,
+			want: "div(img br input svg(path))",
+		},
+		{
+			name: "attribute quoting",
+			html: `<a CLASS='x y' ID=top data-x="a > b" hidden>link</a>`,
+			want: "a#top.x.y",
+		},
+		{
+			name: "raw text and comments",
+			html: `<style>.x > <p> {}</style><!-- <p class="no"> --><script>if (a < b) {}</SCRIPT><p class="yes">`,
+			want: "style script p.yes",
+		},
+		{
+			name: "implied end tags",
+			html: `<ul><li>a<li>b</ul><p>one<p>two`,
+			want: "ul(li li) p p",
+		},
+		{
+			name: "unmatched end tag",
+			html: `<div></span><b></b></div>`,
+			want: "div(b)",
+		},
+		{
+			name: "literal less-than",
+			html: `<p>1 < 2</p>`,
+			want: "p",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := describeHTML(parseHTML(tt.html)); got != tt.want {
+				t.Errorf("parseHTML() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
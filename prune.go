@@ -0,0 +1,182 @@
+package strata
+
+import (
+	"fmt"
+	"go/scanner"
+	gotoken "go/token"
+	"io/fs"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// markupExtensions are the file extensions ScanUsage reads.
+var markupExtensions = map[string]bool{
+	".html":   true,
+	".htm":    true,
+	".gohtml": true,
+	".tmpl":   true,
+	".tpl":    true,
+	".templ":  true,
+	".go":     true,
+}
+
+// Usage is the set of names that templates may render as class names or
+// ids. It is deliberately generous: every word that could be a class name
+// counts, so a rule is only pruned when nothing could produce it.
+type Usage struct {
+	names map[string]bool
+}
+
+// ScanUsage walks fsys and collects the words of every markup file: HTML
+// and html/template files (.html, .htm, .gohtml, .tmpl, .tpl), templ
+// sources (.templ), and the string literals of Go files, which covers
+// templ-generated code. Strata source files such as .css.tmpl are skipped.
+func ScanUsage(fsys fs.FS) (*Usage, error) {
+	u := &Usage{names: make(map[string]bool)}
+	err := fs.WalkDir(fsys, ".", func(filePath string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || sourceExtension(filePath) != "" || !markupExtensions[path.Ext(filePath)] {
+			return nil
+		}
+
+		content, err := fs.ReadFile(fsys, filePath)
+		if err != nil {
+			return fmt.Errorf("read %s: %w", filePath, err)
+		}
+		if path.Ext(filePath) == ".go" {
+			u.addGoStrings(content)
+		} else {
+			u.Add(string(content))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("scan usage: %w", err)
+	}
+	return u, nil
+}
+
+// Add records every word in text, such as a class attribute value built
+// outside of scanned files.
+func (u *Usage) Add(text string) {
+	if u.names == nil {
+		u.names = make(map[string]bool)
+	}
+	for _, word := range strings.FieldsFunc(text, isNotClassChar) {
+		u.names[word] = true
+		// Also record the plain parts of words like "card." or "x:y"
+		for _, part := range strings.FieldsFunc(word, isNotNameChar) {
+			u.names[part] = true
+		}
+	}
+}
+
+// Has reports whether name was seen.
+func (u *Usage) Has(name string) bool {
+	return u.names[name]
+}
+
+// addGoStrings records the words of each string literal in Go source.
+func (u *Usage) addGoStrings(src []byte) {
+	fset := gotoken.NewFileSet()
+	file := fset.AddFile("", fset.Base(), len(src))
+
+	var s scanner.Scanner
+	s.Init(file, src, nil, 0)
+	for {
+		_, tok, lit := s.Scan()
+		if tok == gotoken.EOF {
+			return
+		}
+		if tok != gotoken.STRING {
+			continue
+		}
+		if text, err := strconv.Unquote(lit); err == nil {
+			u.Add(text)
+		}
+	}
+}
+
+// isNotClassChar reports whether r cannot appear in a class name as
+// written in markup. Utility class names may contain ':', '/', '.', '[',
+// ']', '%', '#', '!' and '@'.
+func isNotClassChar(r rune) bool {
+	if r < 0x80 && !isNameChar(byte(r)) {
+		return !strings.ContainsRune(":/.[]%#!@", r)
+	}
+	return false
+}
+
+// isNotNameChar reports whether r cannot appear in a plain identifier.
+func isNotNameChar(r rune) bool {
+	return r < 0x80 && !isNameChar(byte(r))
+}
+
+// Prune returns a copy of the result without the style rules that can
+// never match: rules whose every selector needs a class name or id that
+// the usage lacks. Allow lists patterns for names built at runtime, where
+// '*' matches any run of characters, such as "btn-*" for "btn-" + variant.
+//
+// Names rewritten by Source.ScopeClasses count as used when their
+// original name is. Like Critical, the copy's Header still declares every
+// layer, selectors the matcher cannot read are kept, and an OutputImports
+// result is rendered flat.
+func (r *Result) Prune(u *Usage, allow ...string) *Result {
+	original := make(map[string]string)
+	for _, classes := range r.Classes {
+		for name, scoped := range classes {
+			original[scoped] = name
+		}
+	}
+
+	used := func(name string) bool {
+		if u.Has(name) || u.Has(original[name]) {
+			return true
+		}
+		for _, pattern := range allow {
+			if matchWildcard(pattern, name) {
+				return true
+			}
+		}
+		return false
+	}
+
+	return r.filter(func(selectors string) bool {
+		list, ok := parseSelectorList(selectors)
+		if !ok {
+			return true
+		}
+		for _, sel := range list {
+			if sel.canMatch(used) {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// matchWildcard reports whether name matches pattern, in which '*'
+// matches any run of characters, including none.
+func matchWildcard(pattern, name string) bool {
+	parts := strings.Split(pattern, "*")
+	if len(parts) == 1 {
+		return pattern == name
+	}
+
+	first, last := parts[0], parts[len(parts)-1]
+	if !strings.HasPrefix(name, first) {
+		return false
+	}
+	name = name[len(first):]
+	for _, part := range parts[1 : len(parts)-1] {
+		i := strings.Index(name, part)
+		if i < 0 {
+			return false
+		}
+		name = name[i+len(part):]
+	}
+	return strings.HasSuffix(name, last)
+}
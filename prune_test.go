@@ -0,0 +1,126 @@
+package strata
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestScanUsage(t *testing.T) {
+	t.Parallel()
+
+	templatesFS := fstest.MapFS{
+		"index.html": {Data: []byte(`<div id="app" class="card md:flex w-1/2">Done.</div>`)},
+		"page.gohtml": {Data: []byte(
+			`<a class="link {{ if .Active }}link-active{{ end }}">{{ .Title }}</a>`,
+		)},
+		"button_templ.go": {Data: []byte(`package ui
+
+// comment-only-class
+func Button() {
+	_ = templ.KV("btn-primary", true)
+	_ = "<button class=\"btn\">"
+	_ = ` + "`raw-class`" + `
+}
+`)},
+		"theme.css.tmpl": {Data: []byte(".from-css {}")},
+		"logo.png":       {Data: []byte("png-only-class")},
+	}
+
+	u, err := ScanUsage(templatesFS)
+	if err != nil {
+		t.Fatalf("ScanUsage() error = %v, want nil", err)
+	}
+
+	for _, name := range []string{
+		"app", "card", "md:flex", "w-1/2", "Done", "link", "link-active",
+		"btn-primary", "btn", "raw-class",
+	} {
+		if !u.Has(name) {
+			t.Errorf("Usage.Has(%q) = false, want true", name)
+		}
+	}
+	for _, name := range []string{"comment-only-class", "Button", "from-css", "png-only-class"} {
+		if u.Has(name) {
+			t.Errorf("Usage.Has(%q) = true, want false", name)
+		}
+	}
+}
+
+func TestResult_Prune(t *testing.T) {
+	t.Parallel()
+
+	testFS := fstest.MapFS{
+		"base/base.css": {Data: []byte("body { margin: 0; }\n#app { min-height: 100vh; }\n#legacy { display: none; }")},
+		"components/card.css": {Data: []byte(
+			".card { padding: 1rem; }\n.card .unused { color: red; }\n.card:hover, .modal { color: blue; }\n" +
+				":is(.modal, .drawer) > .title { margin: 0; }\n.btn-danger { color: red; }\n" +
+				"@media print {\n\t.modal { display: none; }\n}",
+		)},
+	}
+
+	res, err := Compile(Source{FS: testFS})
+	if err != nil {
+		t.Fatalf("Compile() error = %v, want nil", err)
+	}
+
+	u := &Usage{}
+	u.Add(`<div id="app"><div class="card btn-" + variant></div></div>`)
+
+	got := res.Prune(u, "btn-*").CSS()
+	want := "@layer base, components;\n" +
+		"@layer base {\nbody { margin: 0; }\n#app { min-height: 100vh; }\n}\n" +
+		"@layer components {\n.card { padding: 1rem; }\n.card:hover, .modal { color: blue; }\n" +
+		".btn-danger { color: red; }\n}\n"
+	if got != want {
+		t.Errorf("Result.Prune().CSS() =\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestResult_Prune_scoped_classes(t *testing.T) {
+	t.Parallel()
+
+	testFS := fstest.MapFS{
+		"card/card.css": {Data: []byte(".title { margin: 0; }\n.footer { margin: 0; }")},
+	}
+
+	res, err := Compile(Source{FS: testFS, ScopeClasses: true})
+	if err != nil {
+		t.Fatalf("Compile() error = %v, want nil", err)
+	}
+
+	u := &Usage{}
+	u.Add(`<h2 class="title">`)
+
+	got := res.Prune(u).CSS()
+	if !strings.Contains(got, res.Classes["card"]["title"]) || strings.Contains(got, res.Classes["card"]["footer"]) {
+		t.Errorf("Result.Prune().CSS() = %q, want only the used scoped class", got)
+	}
+}
+
+func TestMatchWildcard(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		pattern string
+		name    string
+		want    bool
+	}{
+		{"btn", "btn", true},
+		{"btn", "btn-x", false},
+		{"btn-*", "btn-primary", true},
+		{"btn-*", "btn-", true},
+		{"btn-*", "card", false},
+		{"*-active", "tab-active", true},
+		{"*-active", "tab-active-x", false},
+		{"col-*-*", "col-md-6", true},
+		{"col-*-*", "col-6", false},
+		{"*", "anything", true},
+	}
+
+	for _, tt := range tests {
+		if got := matchWildcard(tt.pattern, tt.name); got != tt.want {
+			t.Errorf("matchWildcard(%q, %q) = %v, want %v", tt.pattern, tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,196 @@
+package strata
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Output selects how Build renders layers.
+type Output int
+
+const (
+	// OutputFlat renders every layer as a top-level block with a dotted
+	// name, preceded by a single order statement:
+	//
+	//	@layer base, components, components.buttons;
+	//	@layer base { ... }
+	//	@layer components { ... }
+	//	@layer components.buttons { ... }
+	OutputFlat Output = iota
+
+	// OutputNested renders sub-layers as blocks nested inside their parent,
+	// with an order statement at each level:
+	//
+	//	@layer base, components;
+	//	@layer base { ... }
+	//	@layer components {
+	//	@layer buttons;
+	//	...
+	//	@layer buttons { ... }
+	//	}
+	OutputNested
+
+	// OutputImports renders an @import of each source file instead of its
+	// content, for development, so devtools show the original file names:
+	//
+	//	@layer base, components;
+	//	@import url("/css/base/reset.css") layer(base);
+	//	@import url("/css/components/button.css") layer(components);
+	//
+	// Files are imported from Config.BaseURL and Source.URL, which must
+	// differ between sources. Serve them with Config.Handler.
+	OutputImports
+)
+
+// layerNames returns the names of layers in order.
+func layerNames(layers []Layer) []string {
+	names := make([]string, len(layers))
+	for i, l := range layers {
+		names[i] = l.Name
+	}
+	return names
+}
+
+// orderNames returns the names the top-level order statement declares for
+// layers in the given output.
+func orderNames(layers []Layer, output Output) []string {
+	if output == OutputNested {
+		return buildLayerTree(layers).childNames()
+	}
+	return layerNames(layers)
+}
+
+// uniqueNames returns names without repeats, keeping first appearances.
+func uniqueNames(names []string) []string {
+	var unique []string
+	seen := make(map[string]bool)
+	for _, name := range names {
+		if !seen[name] {
+			seen[name] = true
+			unique = append(unique, name)
+		}
+	}
+	return unique
+}
+
+// renderFlat writes layers as top-level blocks with dotted names.
+func renderFlat(out *strings.Builder, layers []Layer) {
+	for _, l := range layers {
+		if l.External {
+			continue
+		}
+		out.WriteString("@layer ")
+		out.WriteString(l.Name)
+		out.WriteString(" {\n")
+		out.WriteString(l.Content)
+		out.WriteString("}\n")
+	}
+}
+
+// renderImports writes an @import for each file of each layer, then for
+// each unlayered file.
+func renderImports(out *strings.Builder, layers []Layer, files [][]string, unlayered []string) {
+	for i, l := range layers {
+		for _, file := range files[i] {
+			fmt.Fprintf(out, "@import url(%q) layer(%s);\n", file, l.Name)
+		}
+	}
+	for _, file := range unlayered {
+		fmt.Fprintf(out, "@import url(%q);\n", file)
+	}
+}
+
+// layerNode is one segment of the layer tree used for nested output.
+type layerNode struct {
+	name     string
+	layers   []Layer
+	children []*layerNode
+}
+
+// child returns the child node with the given segment name, creating it
+// if needed. Children keep the order in which they were first seen.
+func (n *layerNode) child(name string) *layerNode {
+	for _, c := range n.children {
+		if c.name == name {
+			return c
+		}
+	}
+	c := &layerNode{name: name}
+	n.children = append(n.children, c)
+	return c
+}
+
+// isExternal reports whether the node declares only external layers and
+// has no children, so its parent's order statement fully declares it.
+func (n *layerNode) isExternal() bool {
+	if len(n.children) > 0 {
+		return false
+	}
+	for _, l := range n.layers {
+		if !l.External {
+			return false
+		}
+	}
+	return true
+}
+
+// childNames returns the segment names of the node's children in order.
+func (n *layerNode) childNames() []string {
+	names := make([]string, len(n.children))
+	for i, c := range n.children {
+		names[i] = c.name
+	}
+	return names
+}
+
+// buildLayerTree arranges layers into a tree keyed by name segment.
+// Sibling order follows the first appearance of each segment in layers.
+func buildLayerTree(layers []Layer) *layerNode {
+	root := &layerNode{}
+	for _, l := range layers {
+		n := root
+		for _, segment := range strings.Split(l.Name, ".") {
+			n = n.child(segment)
+		}
+		n.layers = append(n.layers, l)
+	}
+	return root
+}
+
+// renderNested writes layers as nested blocks with per-level order
+// statements below the top level.
+func renderNested(out *strings.Builder, layers []Layer) {
+	root := buildLayerTree(layers)
+	for _, c := range root.children {
+		writeNestedBlock(out, c)
+	}
+}
+
+// writeNestedBlock writes a node's block: the order statement for its
+// children, its own content, then each child's block. External leaf nodes
+// are declared by their parent's order statement alone.
+func writeNestedBlock(out *strings.Builder, n *layerNode) {
+	if n.isExternal() {
+		return
+	}
+	out.WriteString("@layer ")
+	out.WriteString(n.name)
+	out.WriteString(" {\n")
+	if len(n.children) > 0 {
+		writeOrderStatement(out, n.childNames())
+	}
+	for _, l := range n.layers {
+		out.WriteString(l.Content)
+	}
+	for _, c := range n.children {
+		writeNestedBlock(out, c)
+	}
+	out.WriteString("}\n")
+}
+
+// writeOrderStatement writes an "@layer a, b, c;" statement.
+func writeOrderStatement(out *strings.Builder, names []string) {
+	out.WriteString("@layer ")
+	out.WriteString(strings.Join(names, ", "))
+	out.WriteString(";\n")
+}
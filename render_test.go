@@ -0,0 +1,148 @@
+package strata
+
+import (
+	"testing"
+	"testing/fstest"
+)
+
+func TestConfig_Build_output(t *testing.T) {
+	t.Parallel()
+
+	testFS := fstest.MapFS{
+		"reset.css":                  {Data: []byte("/* reset */")},
+		"components/card.css":        {Data: []byte("/* card */")},
+		"components/buttons/btn.css": {Data: []byte("/* btn */")},
+		"components/forms/input.css": {Data: []byte("/* input */")},
+	}
+
+	tests := []struct {
+		name       string
+		giveOutput Output
+		want       string
+	}{
+		{
+			name:       "flat",
+			giveOutput: OutputFlat,
+			want: "@layer components, reset, components.buttons, components.forms;\n" +
+				"@layer components {\n/* card */\n}\n" +
+				"@layer reset {\n/* reset */\n}\n" +
+				"@layer components.buttons {\n/* btn */\n}\n" +
+				"@layer components.forms {\n/* input */\n}\n",
+		},
+		{
+			name:       "nested",
+			giveOutput: OutputNested,
+			want: "@layer components, reset;\n" +
+				"@layer components {\n" +
+				"@layer buttons, forms;\n" +
+				"/* card */\n" +
+				"@layer buttons {\n/* btn */\n}\n" +
+				"@layer forms {\n/* input */\n}\n" +
+				"}\n" +
+				"@layer reset {\n/* reset */\n}\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := Config{Output: tt.giveOutput}.Build(Source{FS: testFS})
+			if err != nil {
+				t.Fatalf("Config.Build() error = %v, want nil", err)
+			}
+
+			if got != tt.want {
+				t.Errorf("Config.Build() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConfig_Build_nested_without_parent_content(t *testing.T) {
+	t.Parallel()
+
+	testFS := fstest.MapFS{
+		"a/b/c/d.css": {Data: []byte("x")},
+	}
+
+	got, err := Config{Output: OutputNested}.Build(Source{FS: testFS, Prefix: "page"})
+	if err != nil {
+		t.Fatalf("Config.Build() error = %v, want nil", err)
+	}
+
+	want := "@layer page;\n" +
+		"@layer page {\n@layer a;\n" +
+		"@layer a {\n@layer b;\n" +
+		"@layer b {\n@layer c;\n" +
+		"@layer c {\nx\n}\n" +
+		"}\n}\n}\n"
+	if got != want {
+		t.Errorf("Config.Build() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestConfig_Build_nested_merges_sources(t *testing.T) {
+	t.Parallel()
+
+	stylesFS := fstest.MapFS{
+		"reset.css": {Data: []byte("/* reset */")},
+	}
+	componentsFS := fstest.MapFS{
+		"button.css": {Data: []byte("/* button */")},
+	}
+	overridesFS := fstest.MapFS{
+		"card.css": {Data: []byte("/* card */")},
+	}
+
+	got, err := Config{Output: OutputNested}.Build(
+		Source{FS: stylesFS},
+		Source{FS: componentsFS, Prefix: "comp"},
+		Source{FS: overridesFS, Prefix: "comp"},
+	)
+	if err != nil {
+		t.Fatalf("Config.Build() error = %v, want nil", err)
+	}
+
+	want := "@layer reset, comp;\n" +
+		"@layer reset {\n/* reset */\n}\n" +
+		"@layer comp {\n@layer button, card;\n" +
+		"@layer button {\n/* button */\n}\n" +
+		"@layer card {\n/* card */\n}\n" +
+		"}\n"
+	if got != want {
+		t.Errorf("Config.Build() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestConfig_Build_nested_external_layers(t *testing.T) {
+	t.Parallel()
+
+	stylesFS := fstest.MapFS{
+		"reset.css": {Data: []byte("/* reset */")},
+	}
+	componentsFS := fstest.MapFS{
+		"button.css": {Data: []byte("/* button */")},
+	}
+
+	got, err := Config{Output: OutputNested}.Build(
+		Source{FS: stylesFS},
+		ExternalLayer("tw.base"),
+		ExternalLayer("tw.utilities"),
+		ExternalLayer("comp.legacy"),
+		Source{FS: componentsFS, Prefix: "comp"},
+	)
+	if err != nil {
+		t.Fatalf("Config.Build() error = %v, want nil", err)
+	}
+
+	want := "@layer reset, tw, comp;\n" +
+		"@layer reset {\n/* reset */\n}\n" +
+		"@layer tw {\n@layer base, utilities;\n}\n" +
+		"@layer comp {\n@layer legacy, button;\n" +
+		"@layer button {\n/* button */\n}\n" +
+		"}\n"
+	if got != want {
+		t.Errorf("Config.Build() =\n%s\nwant\n%s", got, want)
+	}
+}
@@ -0,0 +1,165 @@
+package strata
+
+import "strings"
+
+// Result is the structured output of compiling sources.
+type Result struct {
+	// Layers lists every layer in declaration order.
+	Layers []Layer
+
+	// Unlayered holds content written after all layer blocks, without any
+	// @layer wrapper.
+	Unlayered string
+
+	// FileLayers lists the layers declared by @layer rules inside source
+	// files, fully qualified by the layer each file belongs to, in order of
+	// first declaration.
+	FileLayers []FileLayer
+
+	// Classes maps each layer of a Source with ScopeClasses set to its
+	// class names: original name to scoped name, e.g.
+	// Classes["components.card"]["title"] == "card_title_4f1c2a".
+	Classes map[string]map[string]string
+
+	// Assets maps the content-hashed paths of assets collected under
+	// Config.HashAssets, relative to Config.BaseURL, to their content.
+	// Use WriteAssets to write them to a directory.
+	Assets map[string][]byte
+
+	// Warnings lists non-fatal problems found while compiling, such as
+	// file-declared layers under WarnFileLayers.
+	Warnings []string
+
+	// output selects how Header and Body render the layers.
+	output Output
+
+	// order, if set, replaces the layer names declared by Header, so
+	// bundles compiled together share one order statement.
+	order []string
+
+	// files lists the file URLs of each layer in Layers, and
+	// unlayeredFiles those of unlayered content, for OutputImports.
+	files          [][]string
+	unlayeredFiles []string
+}
+
+// Layer is a single cascade layer in a Result.
+type Layer struct {
+	// Name is the fully-qualified, dot-separated layer name.
+	Name string
+
+	// Content is the concatenated content of the layer's files.
+	Content string
+
+	// External reports whether the layer was declared by ExternalLayer and
+	// only appears in the order statement.
+	External bool
+}
+
+// Compile walks the sources like Build and returns the structured result.
+func Compile(sources ...Source) (*Result, error) {
+	return Config{}.Compile(sources...)
+}
+
+// Compile walks the sources like Build and returns the structured result,
+// rendered according to the config.
+func (c Config) Compile(sources ...Source) (*Result, error) {
+	// Imported files are addressed by Source.URL, which must be unique
+	if c.Output == OutputImports {
+		if err := checkSourceURLs(sources); err != nil {
+			return nil, err
+		}
+	}
+
+	comp := &compiler{config: c}
+	for _, src := range sources {
+		if err := comp.addSource(src); err != nil {
+			return nil, err
+		}
+	}
+
+	// Separate unlayered content, which is written after every layer block
+	r := &Result{
+		FileLayers: comp.fileLayers,
+		Classes:    comp.classes,
+		Assets:     comp.assets,
+		Warnings:   comp.warnings,
+		output:     c.Output,
+	}
+	var unlayered strings.Builder
+	for _, l := range comp.layers {
+		if l.unlayered {
+			unlayered.Write(l.content.Bytes())
+			r.unlayeredFiles = append(r.unlayeredFiles, l.files...)
+			continue
+		}
+		r.Layers = append(r.Layers, Layer{
+			Name:     l.name,
+			Content:  l.content.String(),
+			External: l.external,
+		})
+		r.files = append(r.files, l.files)
+	}
+	r.Unlayered = unlayered.String()
+
+	return r, nil
+}
+
+// Header returns the layer order statement, such as "@layer a, b, c;\n".
+// It must reach the browser before any layer content. Header returns an
+// empty string when there are no layers.
+func (r *Result) Header() string {
+	names := r.order
+	if names == nil {
+		names = orderNames(r.Layers, r.output)
+	}
+	if len(names) == 0 {
+		return ""
+	}
+
+	var out strings.Builder
+	writeOrderStatement(&out, names)
+	return out.String()
+}
+
+// Body returns the layer blocks followed by unlayered content, without the
+// order statement. Serve it alongside Header, for example by inlining the
+// header in the page and loading the body from a cacheable file.
+func (r *Result) Body() string {
+	var out strings.Builder
+	switch r.output {
+	case OutputNested:
+		renderNested(&out, r.Layers)
+	case OutputImports:
+		renderImports(&out, r.Layers, r.files, r.unlayeredFiles)
+		return out.String()
+	default:
+		renderFlat(&out, r.Layers)
+	}
+	out.WriteString(r.Unlayered)
+	return out.String()
+}
+
+// CSS returns the complete stylesheet: Header followed by Body.
+func (r *Result) CSS() string {
+	return r.Header() + r.Body()
+}
+
+// CustomProperties returns the custom properties (e.g. "--color-primary")
+// declared in the named layer, in order of first declaration.
+func (r *Result) CustomProperties(layerName string) []string {
+	var props []string
+	seen := make(map[string]bool)
+	for _, l := range r.Layers {
+		if l.Name != layerName {
+			continue
+		}
+		for _, prop := range declaredProperties(l.Content) {
+			if !seen[prop] {
+				seen[prop] = true
+				props = append(props, prop)
+			}
+		}
+	}
+	return props
+}
@@ -0,0 +1,209 @@
+package strata
+
+import (
+	"reflect"
+	"testing"
+	"testing/fstest"
+)
+
+func TestResult_Header_Body(t *testing.T) {
+	t.Parallel()
+
+	stylesFS := fstest.MapFS{
+		"reset.css":             {Data: []byte("/* reset */")},
+		"base/elements/btn.css": {Data: []byte("/* btn */")},
+	}
+	overridesFS := fstest.MapFS{
+		"print.css": {Data: []byte("/* print */")},
+	}
+
+	tests := []struct {
+		name       string
+		giveOutput Output
+		wantHeader string
+		wantBody   string
+	}{
+		{
+			name:       "flat",
+			giveOutput: OutputFlat,
+			wantHeader: "@layer reset, base.elements;\n",
+			wantBody: "@layer reset {\n/* reset */\n}\n" +
+				"@layer base.elements {\n/* btn */\n}\n" +
+				"/* print */\n",
+		},
+		{
+			name:       "nested",
+			giveOutput: OutputNested,
+			wantHeader: "@layer reset, base;\n",
+			wantBody: "@layer reset {\n/* reset */\n}\n" +
+				"@layer base {\n@layer elements;\n" +
+				"@layer elements {\n/* btn */\n}\n" +
+				"}\n" +
+				"/* print */\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := Config{Output: tt.giveOutput}.Compile(
+				Source{FS: stylesFS},
+				Source{FS: overridesFS, Unlayered: true},
+			)
+			if err != nil {
+				t.Fatalf("Config.Compile() error = %v, want nil", err)
+			}
+
+			if got.Header() != tt.wantHeader {
+				t.Errorf("Result.Header() = %q, want %q", got.Header(), tt.wantHeader)
+			}
+			if got.Body() != tt.wantBody {
+				t.Errorf("Result.Body() =\n%s\nwant\n%s", got.Body(), tt.wantBody)
+			}
+			if got.CSS() != tt.wantHeader+tt.wantBody {
+				t.Errorf("Result.CSS() = %q, want Header() + Body()", got.CSS())
+			}
+		})
+	}
+}
+
+func TestCompile_layers(t *testing.T) {
+	t.Parallel()
+
+	testFS := fstest.MapFS{
+		"reset.css":     {Data: []byte("a")},
+		"base/file.css": {Data: []byte("b")},
+	}
+
+	got, err := Compile(Source{FS: testFS}, ExternalLayer("tw"))
+	if err != nil {
+		t.Fatalf("Compile() error = %v, want nil", err)
+	}
+
+	want := []Layer{
+		{Name: "base", Content: "b\n"},
+		{Name: "reset", Content: "a\n"},
+		{Name: "tw", External: true},
+	}
+	if len(got.Layers) != len(want) {
+		t.Fatalf("Compile() layers = %+v, want %+v", got.Layers, want)
+	}
+	for i := range want {
+		if got.Layers[i] != want[i] {
+			t.Errorf("Compile() layer %d = %+v, want %+v", i, got.Layers[i], want[i])
+		}
+	}
+}
+
+func TestResult_empty(t *testing.T) {
+	t.Parallel()
+
+	got, err := Compile(Source{FS: fstest.MapFS{}})
+	if err != nil {
+		t.Fatalf("Compile() error = %v, want nil", err)
+	}
+
+	if got.Header() != "" || got.Body() != "" || got.CSS() != "" {
+		t.Errorf("Compile() of empty source rendered %q, want empty", got.CSS())
+	}
+}
+
+func TestHash(t *testing.T) {
+	t.Parallel()
+
+	if got := Hash(""); got != "" {
+		t.Errorf("Hash(\"\") = %q, want empty", got)
+	}
+
+	css, hash, err := BuildWithHash(Source{FS: fstest.MapFS{
+		"reset.css": {Data: []byte("* { margin: 0; }")},
+	}})
+	if err != nil {
+		t.Fatalf("BuildWithHash() error = %v", err)
+	}
+	if got := Hash(css); got != hash {
+		t.Errorf("Hash() = %q, want %q to match BuildWithHash()", got, hash)
+	}
+}
+
+func TestResult_CustomProperties(t *testing.T) {
+	t.Parallel()
+
+	testFS := fstest.MapFS{
+		"tokens.css": {Data: []byte(":root {\n\t--color-primary: blue;\n\t--radius: 4px;\n}\n" +
+			"@media (prefers-color-scheme: dark) { :root { --color-primary: navy; } }\n" +
+			"a { color: var(--color-primary); }")},
+		"theme.tokens.json": {Data: []byte(`{"space": {"sm": {"$value": "4px"}}}`)},
+		"base/file.css":     {Data: []byte(":root { --ignored: 1; }")},
+	}
+
+	got, err := Compile(Source{FS: testFS})
+	if err != nil {
+		t.Fatalf("Compile() error = %v, want nil", err)
+	}
+
+	tests := []struct {
+		name      string
+		giveLayer string
+		want      []string
+	}{
+		{
+			name:      "css_layer",
+			giveLayer: "tokens",
+			want:      []string{"--color-primary", "--radius"},
+		},
+		{
+			name:      "design_tokens_layer",
+			giveLayer: "theme",
+			want:      []string{"--space-sm"},
+		},
+		{
+			name:      "missing_layer",
+			giveLayer: "nope",
+			want:      nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			props := got.CustomProperties(tt.giveLayer)
+			if !reflect.DeepEqual(props, tt.want) {
+				t.Errorf("Result.CustomProperties(%q) = %v, want %v", tt.giveLayer, props, tt.want)
+			}
+		})
+	}
+}
+
+func TestIntegrity(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		giveCSS string
+		want    string
+	}{
+		{
+			name:    "empty",
+			giveCSS: "",
+			want:    "",
+		},
+		{
+			name:    "known_digest",
+			giveCSS: "a",
+			want:    "sha384-VKWbnyKwuAiA2EJ+VIt8I6vYc0huHwNdzpzWl+hRdQM8qojm1XvDXvrgta/TFF8x",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := Integrity(tt.giveCSS); got != tt.want {
+				t.Errorf("Integrity(%q) = %q, want %q", tt.giveCSS, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,200 @@
+package strata
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+)
+
+// scopedClassName returns the scoped form of a class in a layer: the last
+// segment of the layer name, the class, and a short hash of both
+// (e.g., layer "components.card", class "title" -> "card_title_4f1c2a").
+func scopedClassName(layerName, class string) string {
+	return lastSegment(layerName) + "_" + class + "_" + Hash(layerName + "." + class)[:6]
+}
+
+// lastSegment returns the last dot-separated segment of a layer name,
+// which names the component directory (e.g., "components.card" -> "card").
+func lastSegment(layerName string) string {
+	return layerName[strings.LastIndexByte(layerName, '.')+1:]
+}
+
+// scopeClasses rewrites the class selectors in css to names scoped to the
+// layer, recording each original class name and its scoped name in classes.
+// Only selectors are rewritten: rule preludes, including nested rules, and
+// @scope preludes. Declarations and other at-rules are left untouched.
+func scopeClasses(css, layerName string, classes map[string]string) string {
+	var out strings.Builder
+	last := 0
+	var walk func(nodes []*cssNode)
+	walk = func(nodes []*cssNode) {
+		for _, n := range nodes {
+			if !n.block {
+				continue
+			}
+			if kw := n.atKeyword(css); kw == "" || kw == "scope" {
+				for _, c := range findClassSelectors(css, n.preludeStart, n.preludeEnd) {
+					class := cssUnescape(css[c.start:c.end])
+					scoped := scopedClassName(layerName, class)
+					classes[class] = scoped
+					out.WriteString(css[last:c.start])
+					out.WriteString(cssEscape(scoped))
+					last = c.end
+				}
+			}
+			walk(n.children)
+		}
+	}
+	walk(parseCSS(css))
+	out.WriteString(css[last:])
+	return out.String()
+}
+
+// span is a byte range within a source string.
+type span struct {
+	start, end int
+}
+
+// findClassSelectors returns the ranges of class names (without the
+// leading ".") in the selector src[start:end], skipping strings and
+// attribute selectors.
+func findClassSelectors(src string, start, end int) []span {
+	var found []span
+	for pos := start; pos < end; {
+		switch c := src[pos]; {
+		case c == '"' || c == '\'':
+			pos = skipString(src, pos)
+		case c == '[':
+			closing := strings.IndexByte(src[pos:end], ']')
+			if closing < 0 {
+				return found
+			}
+			pos += closing + 1
+		case c == '\\':
+			pos += 2
+		case c == '.' && startsIdent(src[pos+1:end]):
+			identEnd := scanIdent(src, pos+1, end)
+			found = append(found, span{pos + 1, identEnd})
+			pos = identEnd
+		default:
+			pos++
+		}
+	}
+	return found
+}
+
+// startsIdent reports whether s begins with a CSS identifier.
+func startsIdent(s string) bool {
+	if s == "" {
+		return false
+	}
+	switch c := s[0]; {
+	case c == '-':
+		return len(s) > 1 && (s[1] == '-' || isNameStart(s[1]) || s[1] == '\\')
+	case c == '\\':
+		return len(s) > 1 && s[1] != '\n'
+	default:
+		return isNameStart(c)
+	}
+}
+
+// isNameStart reports whether c can start a CSS identifier.
+func isNameStart(c byte) bool {
+	return c == '_' || c >= 0x80 || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+// scanIdent returns the end of the identifier starting at pos, including
+// escape sequences.
+func scanIdent(src string, pos, end int) int {
+	for pos < end {
+		switch {
+		case src[pos] == '\\' && pos+1 < end:
+			pos += 2
+		case isNameChar(src[pos]):
+			pos++
+		default:
+			return pos
+		}
+	}
+	return pos
+}
+
+// cssUnescape resolves CSS escape sequences in an identifier
+// (e.g., `md\:show` -> "md:show", `\31 0` -> "10").
+func cssUnescape(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i+1 == len(s) {
+			b.WriteByte(s[i])
+			continue
+		}
+		i++
+		hexEnd := i
+		for hexEnd < len(s) && hexEnd-i < 6 && isHexDigit(s[hexEnd]) {
+			hexEnd++
+		}
+		if hexEnd == i {
+			b.WriteByte(s[i])
+			continue
+		}
+		r, _ := strconv.ParseUint(s[i:hexEnd], 16, 32)
+		b.WriteRune(rune(r))
+		i = hexEnd - 1
+		if hexEnd < len(s) && isSpace(s[hexEnd]) {
+			i++ // whitespace terminating a hex escape
+		}
+	}
+	return b.String()
+}
+
+// cssEscape escapes the characters of an identifier that are not valid
+// in CSS identifiers (e.g., "md:show" -> `md\:show`, "404" -> `\34 04`).
+func cssEscape(s string) string {
+	var b strings.Builder
+	if s != "" && s[0] >= '0' && s[0] <= '9' {
+		b.WriteString(`\3`)
+		b.WriteByte(s[0])
+		b.WriteByte(' ')
+		s = s[1:]
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if !isNameChar(c) {
+			b.WriteByte('\\')
+		}
+		b.WriteByte(c)
+	}
+	return b.String()
+}
+
+// isHexDigit reports whether c is a hexadecimal digit.
+func isHexDigit(c byte) bool {
+	return (c >= '0' && c <= '9') || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')
+}
+
+// scopeClasses scopes a file's class selectors to its layer and records
+// the mapping for Result.Classes.
+func (c *compiler) scopeClasses(layerName, css string) string {
+	if c.classes == nil {
+		c.classes = make(map[string]map[string]string)
+	}
+	classes, ok := c.classes[layerName]
+	if !ok {
+		classes = make(map[string]string)
+		c.classes[layerName] = classes
+	}
+	return scopeClasses(css, layerName, classes)
+}
+
+// wrapInScope wraps a layer's content in an @scope rule rooted at
+// elements whose attribute equals the last segment of the layer name.
+func wrapInScope(l *layer, attribute string) {
+	var wrapped bytes.Buffer
+	wrapped.WriteString(`@scope ([` + attribute + `="` + lastSegment(l.name) + `"]) {` + "\n")
+	wrapped.Write(l.content.Bytes())
+	wrapped.WriteString("}\n")
+	l.content = &wrapped
+}
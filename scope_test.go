@@ -0,0 +1,191 @@
+package strata
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestScopeClasses(t *testing.T) {
+	t.Parallel()
+
+	title := scopedClassName("components.card", "title")
+	active := scopedClassName("components.card", "active")
+	card := scopedClassName("components.card", "card")
+	show := scopedClassName("components.card", "md:show")
+
+	tests := []struct {
+		name        string
+		giveCSS     string
+		wantCSS     string
+		wantClasses map[string]string
+	}{
+		{
+			name:        "simple",
+			giveCSS:     ".title { color: red; }",
+			wantCSS:     "." + title + " { color: red; }",
+			wantClasses: map[string]string{"title": title},
+		},
+		{
+			name:        "compound_and_pseudo",
+			giveCSS:     "a.title:not(.active), .title > .active::before { margin: 0.5em; }",
+			wantCSS:     "a." + title + ":not(." + active + "), ." + title + " > ." + active + "::before { margin: 0.5em; }",
+			wantClasses: map[string]string{"title": title, "active": active},
+		},
+		{
+			name:        "nested_rules",
+			giveCSS:     ".card {\n\tpadding: .5rem;\n\t&.active { color: red; }\n\t.title { margin: 0; }\n}",
+			wantCSS:     "." + card + " {\n\tpadding: .5rem;\n\t&." + active + " { color: red; }\n\t." + title + " { margin: 0; }\n}",
+			wantClasses: map[string]string{"card": card, "active": active, "title": title},
+		},
+		{
+			name:        "inside_media",
+			giveCSS:     "@media (min-width: 40.5em) { .title { x: y; } }",
+			wantCSS:     "@media (min-width: 40.5em) { ." + title + " { x: y; } }",
+			wantClasses: map[string]string{"title": title},
+		},
+		{
+			name:        "ignores_strings_and_attributes",
+			giveCSS:     `[data-x=".title"] a[href$=".pdf"]::after { content: ".title"; }`,
+			wantCSS:     `[data-x=".title"] a[href$=".pdf"]::after { content: ".title"; }`,
+			wantClasses: map[string]string{},
+		},
+		{
+			name:        "keyframes_untouched",
+			giveCSS:     "@keyframes spin { 0% { a: b; } 50.5% { a: c; } }",
+			wantCSS:     "@keyframes spin { 0% { a: b; } 50.5% { a: c; } }",
+			wantClasses: map[string]string{},
+		},
+		{
+			name:        "escaped_class",
+			giveCSS:     `.md\:show { display: block; }`,
+			wantCSS:     "." + cssEscape(show) + " { display: block; }",
+			wantClasses: map[string]string{"md:show": show},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			classes := make(map[string]string)
+			got := scopeClasses(tt.giveCSS, "components.card", classes)
+			if got != tt.wantCSS {
+				t.Errorf("scopeClasses() =\n%s\nwant\n%s", got, tt.wantCSS)
+			}
+			if !reflect.DeepEqual(classes, tt.wantClasses) {
+				t.Errorf("scopeClasses() classes = %v, want %v", classes, tt.wantClasses)
+			}
+		})
+	}
+}
+
+func TestScopedClassName(t *testing.T) {
+	t.Parallel()
+
+	got := scopedClassName("components.card", "title")
+	if !strings.HasPrefix(got, "card_title_") || len(got) != len("card_title_")+6 {
+		t.Errorf("scopedClassName() = %q, want card_title_ and a 6 character hash", got)
+	}
+
+	if other := scopedClassName("components.dialog", "title"); other == got {
+		t.Errorf("scopedClassName() = %q for different layers, want distinct names", got)
+	}
+}
+
+func TestCSSEscape(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		give string
+		want string
+	}{
+		{give: "card_title", want: "card_title"},
+		{give: "md:show", want: `md\:show`},
+		{give: "404_title", want: `\34 04_title`},
+	}
+
+	for _, tt := range tests {
+		if got := cssEscape(tt.give); got != tt.want {
+			t.Errorf("cssEscape(%q) = %q, want %q", tt.give, got, tt.want)
+		}
+		if got := cssUnescape(cssEscape(tt.give)); got != tt.give {
+			t.Errorf("cssUnescape(cssEscape(%q)) = %q, want round trip", tt.give, got)
+		}
+	}
+}
+
+func TestCompile_scope_classes(t *testing.T) {
+	t.Parallel()
+
+	stylesFS := fstest.MapFS{
+		"base.css": {Data: []byte(".title { font-size: 2rem; }")},
+	}
+	componentsFS := fstest.MapFS{
+		"card/card.css":     {Data: []byte(".title { color: red; }")},
+		"dialog/dialog.css": {Data: []byte(".title { color: blue; }")},
+	}
+
+	got, err := Compile(
+		Source{FS: stylesFS},
+		Source{FS: componentsFS, Prefix: "comp", ScopeClasses: true},
+	)
+	if err != nil {
+		t.Fatalf("Compile() error = %v, want nil", err)
+	}
+
+	wantClasses := map[string]map[string]string{
+		"comp.card":   {"title": scopedClassName("comp.card", "title")},
+		"comp.dialog": {"title": scopedClassName("comp.dialog", "title")},
+	}
+	if !reflect.DeepEqual(got.Classes, wantClasses) {
+		t.Errorf("Result.Classes = %v, want %v", got.Classes, wantClasses)
+	}
+
+	css := got.CSS()
+	for _, want := range []string{
+		"@layer base {\n.title {",
+		"@layer comp.card {\n." + wantClasses["comp.card"]["title"] + " {",
+		"@layer comp.dialog {\n." + wantClasses["comp.dialog"]["title"] + " {",
+	} {
+		if !strings.Contains(css, want) {
+			t.Errorf("Result.CSS() missing %q, got:\n%s", want, css)
+		}
+	}
+}
+
+func TestCompile_scope_attribute(t *testing.T) {
+	t.Parallel()
+
+	componentsFS := fstest.MapFS{
+		"card/card.css":   {Data: []byte(".title { color: red; }")},
+		"card/footer.css": {Data: []byte("footer { margin: 0; }")},
+		"forms/input.css": {Data: []byte("input { border: 0; }")},
+	}
+	stylesFS := fstest.MapFS{
+		"reset.css": {Data: []byte("* { margin: 0; }")},
+	}
+
+	got, err := Build(
+		Source{FS: stylesFS},
+		Source{FS: componentsFS, Prefix: "components", ScopeAttribute: "data-component"},
+	)
+	if err != nil {
+		t.Fatalf("Build() error = %v, want nil", err)
+	}
+
+	want := "@layer reset, components.card, components.forms;\n" +
+		"@layer reset {\n* { margin: 0; }\n}\n" +
+		"@layer components.card {\n" +
+		"@scope ([data-component=\"card\"]) {\n" +
+		".title { color: red; }\nfooter { margin: 0; }\n" +
+		"}\n}\n" +
+		"@layer components.forms {\n" +
+		"@scope ([data-component=\"forms\"]) {\n" +
+		"input { border: 0; }\n" +
+		"}\n}\n"
+	if got != want {
+		t.Errorf("Build() =\n%s\nwant\n%s", got, want)
+	}
+}
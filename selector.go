@@ -0,0 +1,453 @@
+package strata
+
+import "strings"
+
+// complexSelector is a sequence of compound selectors joined by
+// combinators, such as "nav > .item a".
+type complexSelector []compoundSelector
+
+// compoundSelector is a run of simple selectors that all apply to one
+// element, such as "a.active[href]".
+type compoundSelector struct {
+	// combinator relates the compound to the one before it: ' ', '>', '+'
+	// or '~'. It is zero for the first compound.
+	combinator byte
+
+	// tag is the lowercase type selector; empty or "*" matches any tag.
+	tag string
+
+	ids     []string
+	classes []string
+	attrs   []attrSelector
+
+	// anyOf holds the arguments of :is() and :where(), each of which
+	// needs one matching alternative.
+	anyOf [][]complexSelector
+
+	// pseudos lists the structural pseudo-classes checked against the
+	// document, such as "first-child". Other pseudo-classes are assumed
+	// to match, since the element may enter that state.
+	pseudos []string
+}
+
+// attrSelector is an attribute selector such as [type="text" i].
+type attrSelector struct {
+	name  string
+	op    string
+	value string
+	fold  bool
+}
+
+// parseSelectorList parses a comma-separated selector list. It reports
+// false if any selector uses syntax the matcher does not understand.
+func parseSelectorList(s string) ([]complexSelector, bool) {
+	var list []complexSelector
+	for _, part := range splitSelectorList(s) {
+		sel, ok := parseComplexSelector(part)
+		if !ok {
+			return nil, false
+		}
+		list = append(list, sel)
+	}
+	return list, len(list) > 0
+}
+
+// parseComplexSelector parses a single complex selector.
+func parseComplexSelector(s string) (complexSelector, bool) {
+	var sel complexSelector
+	var combinator byte
+	for pos := 0; ; {
+		for pos < len(s) && isSpace(s[pos]) {
+			pos++
+		}
+		if pos >= len(s) {
+			break
+		}
+		if c := s[pos]; c == '>' || c == '+' || c == '~' {
+			if len(sel) == 0 || combinator != 0 {
+				return nil, false // relative selectors are not supported
+			}
+			combinator = c
+			pos++
+			continue
+		}
+		if len(sel) > 0 && combinator == 0 {
+			combinator = ' '
+		}
+
+		compound, next, ok := parseCompoundSelector(s, pos)
+		if !ok {
+			return nil, false
+		}
+		compound.combinator = combinator
+		combinator = 0
+		sel = append(sel, compound)
+		pos = next
+	}
+	return sel, len(sel) > 0 && combinator == 0
+}
+
+// parseCompoundSelector parses the compound selector starting at pos,
+// returning it and the position after it.
+func parseCompoundSelector(s string, pos int) (compoundSelector, int, bool) {
+	var c compoundSelector
+	start := pos
+	for pos < len(s) {
+		switch ch := s[pos]; {
+		case isSpace(ch) || ch == '>' || ch == '+' || ch == '~':
+			return c, pos, pos > start
+
+		case ch == '*' && pos == start:
+			c.tag = "*"
+			pos++
+
+		case pos == start && startsIdent(s[pos:]):
+			end := scanIdent(s, pos, len(s))
+			c.tag = strings.ToLower(cssUnescape(s[pos:end]))
+			pos = end
+
+		case (ch == '#' || ch == '.') && startsIdent(s[pos+1:]):
+			end := scanIdent(s, pos+1, len(s))
+			name := cssUnescape(s[pos+1 : end])
+			if ch == '#' {
+				c.ids = append(c.ids, name)
+			} else {
+				c.classes = append(c.classes, name)
+			}
+			pos = end
+
+		case ch == '[':
+			end := closingBracket(s, pos)
+			if end < 0 {
+				return c, pos, false
+			}
+			attr, ok := parseAttrSelector(s[pos+1 : end])
+			if !ok {
+				return c, pos, false
+			}
+			c.attrs = append(c.attrs, attr)
+			pos = end + 1
+
+		case ch == ':':
+			next, ok := c.parsePseudo(s, pos)
+			if !ok {
+				return c, pos, false
+			}
+			pos = next
+
+		default:
+			return c, pos, false // nesting, namespaces and invalid syntax
+		}
+	}
+	return c, pos, pos > start
+}
+
+// parsePseudo parses the pseudo-class or pseudo-element at pos into c,
+// returning the position after it.
+func (c *compoundSelector) parsePseudo(s string, pos int) (int, bool) {
+	pos++
+	element := pos < len(s) && s[pos] == ':'
+	if element {
+		pos++
+	}
+	if !startsIdent(s[pos:]) {
+		return pos, false
+	}
+	end := scanIdent(s, pos, len(s))
+	name := strings.ToLower(s[pos:end])
+	pos = end
+
+	var arg string
+	if pos < len(s) && s[pos] == '(' {
+		closing := closingBracket(s, pos)
+		if closing < 0 {
+			return pos, false
+		}
+		arg = s[pos+1 : closing]
+		pos = closing + 1
+	}
+
+	// Pseudo-elements style a part of the element, which matches if the
+	// element does
+	if element {
+		return pos, true
+	}
+
+	switch name {
+	case "is", "where", "matches", "-webkit-any", "-moz-any":
+		// An argument the matcher cannot read is assumed to match
+		if list, ok := parseSelectorList(arg); ok {
+			c.anyOf = append(c.anyOf, list)
+		}
+	case "root", "empty", "first-child", "last-child", "only-child",
+		"first-of-type", "last-of-type", "only-of-type":
+		c.pseudos = append(c.pseudos, name)
+	}
+	return pos, true
+}
+
+// parseAttrSelector parses the contents of an attribute selector's
+// brackets.
+func parseAttrSelector(s string) (attrSelector, bool) {
+	s = strings.TrimSpace(s)
+	if !startsIdent(s) {
+		return attrSelector{}, false
+	}
+	end := scanIdent(s, 0, len(s))
+	attr := attrSelector{name: strings.ToLower(cssUnescape(s[:end]))}
+	rest := strings.TrimLeft(s[end:], " \t\n\r\f")
+	if rest == "" {
+		return attr, true
+	}
+
+	for _, op := range []string{"=", "~=", "|=", "^=", "$=", "*="} {
+		if strings.HasPrefix(rest, op) {
+			attr.op = op
+			rest = strings.TrimLeft(rest[len(op):], " \t\n\r\f")
+			break
+		}
+	}
+	if attr.op == "" || rest == "" {
+		return attrSelector{}, false
+	}
+
+	if q := rest[0]; q == '"' || q == '\'' {
+		end := skipString(rest, 0)
+		if end < 2 || rest[end-1] != q {
+			return attrSelector{}, false
+		}
+		attr.value = cssUnescape(rest[1 : end-1])
+		rest = rest[end:]
+	} else {
+		end := scanIdent(rest, 0, len(rest))
+		attr.value = cssUnescape(rest[:end])
+		rest = rest[end:]
+	}
+
+	switch strings.ToLower(strings.TrimSpace(rest)) {
+	case "":
+	case "i":
+		attr.fold = true
+	case "s":
+	default:
+		return attrSelector{}, false
+	}
+	return attr, true
+}
+
+// closingBracket returns the position of the bracket closing the one at
+// pos, skipping strings and nested brackets, or -1.
+func closingBracket(s string, pos int) int {
+	depth := 0
+	for pos < len(s) {
+		switch s[pos] {
+		case '"', '\'':
+			pos = skipString(s, pos)
+			continue
+		case '\\':
+			pos += 2
+			continue
+		case '(', '[':
+			depth++
+		case ')', ']':
+			depth--
+			if depth == 0 {
+				return pos
+			}
+		}
+		pos++
+	}
+	return -1
+}
+
+// matches reports whether the selector matches the element.
+func (sel complexSelector) matches(e *htmlElement) bool {
+	return sel.matchesAt(len(sel)-1, e)
+}
+
+// matchesAt reports whether sel[:i+1] matches with sel[i] on e.
+func (sel complexSelector) matchesAt(i int, e *htmlElement) bool {
+	c := sel[i]
+	if !c.matches(e) {
+		return false
+	}
+	if i == 0 {
+		return true
+	}
+
+	switch c.combinator {
+	case '>':
+		return e.parent.isElement() && sel.matchesAt(i-1, e.parent)
+	case '+':
+		p := e.prev()
+		return p != nil && sel.matchesAt(i-1, p)
+	case '~':
+		for p := e.prev(); p != nil; p = p.prev() {
+			if sel.matchesAt(i-1, p) {
+				return true
+			}
+		}
+	default:
+		for p := e.parent; p.isElement(); p = p.parent {
+			if sel.matchesAt(i-1, p) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// matches reports whether the compound selector matches the element.
+func (c compoundSelector) matches(e *htmlElement) bool {
+	if c.tag != "" && c.tag != "*" && c.tag != e.tag {
+		return false
+	}
+	for _, id := range c.ids {
+		if e.attrs["id"] != id {
+			return false
+		}
+	}
+	for _, class := range c.classes {
+		if !e.hasClass(class) {
+			return false
+		}
+	}
+	for _, attr := range c.attrs {
+		if !attr.matches(e) {
+			return false
+		}
+	}
+	for _, list := range c.anyOf {
+		if !matchesAny(list, e) {
+			return false
+		}
+	}
+	for _, pseudo := range c.pseudos {
+		if !matchesPseudo(pseudo, e) {
+			return false
+		}
+	}
+	return true
+}
+
+// matchesAny reports whether any selector in the list matches the element.
+func matchesAny(list []complexSelector, e *htmlElement) bool {
+	for _, sel := range list {
+		if sel.matches(e) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesPseudo reports whether a structural pseudo-class matches the
+// element.
+func matchesPseudo(name string, e *htmlElement) bool {
+	siblings := e.parent.children
+	switch name {
+	case "root":
+		return !e.parent.isElement()
+	case "empty":
+		return len(e.children) == 0
+	case "first-child":
+		return e.index == 0
+	case "last-child":
+		return e.index == len(siblings)-1
+	case "only-child":
+		return len(siblings) == 1
+	}
+
+	// The remaining pseudo-classes count siblings of the same type
+	before, after := 0, 0
+	for _, s := range siblings {
+		switch {
+		case s.tag != e.tag:
+		case s.index < e.index:
+			before++
+		case s.index > e.index:
+			after++
+		}
+	}
+	switch name {
+	case "first-of-type":
+		return before == 0
+	case "last-of-type":
+		return after == 0
+	default: // only-of-type
+		return before == 0 && after == 0
+	}
+}
+
+// matches reports whether the attribute selector matches the element.
+func (a attrSelector) matches(e *htmlElement) bool {
+	value, ok := e.attrs[a.name]
+	if !ok {
+		return false
+	}
+	want := a.value
+	if a.fold {
+		value, want = strings.ToLower(value), strings.ToLower(want)
+	}
+
+	switch a.op {
+	case "":
+		return true
+	case "=":
+		return value == want
+	case "~=":
+		for _, v := range strings.Fields(value) {
+			if v == want {
+				return true
+			}
+		}
+		return false
+	case "|=":
+		return value == want || strings.HasPrefix(value, want+"-")
+	case "^=":
+		return want != "" && strings.HasPrefix(value, want)
+	case "$=":
+		return want != "" && strings.HasSuffix(value, want)
+	default: // "*="
+		return want != "" && strings.Contains(value, want)
+	}
+}
+
+// canMatch reports whether the selector can match markup whose class
+// names and ids are all accepted by used. Only classes and ids are
+// considered; every other condition is assumed satisfiable.
+func (sel complexSelector) canMatch(used func(name string) bool) bool {
+	for _, c := range sel {
+		if !c.canMatch(used) {
+			return false
+		}
+	}
+	return true
+}
+
+// canMatch reports whether the compound selector's classes and ids are
+// all accepted by used.
+func (c compoundSelector) canMatch(used func(name string) bool) bool {
+	for _, id := range c.ids {
+		if !used(id) {
+			return false
+		}
+	}
+	for _, class := range c.classes {
+		if !used(class) {
+			return false
+		}
+	}
+	for _, list := range c.anyOf {
+		possible := false
+		for _, alt := range list {
+			if alt.canMatch(used) {
+				possible = true
+				break
+			}
+		}
+		if !possible {
+			return false
+		}
+	}
+	return true
+}
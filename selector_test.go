@@ -0,0 +1,99 @@
+package strata
+
+import "testing"
+
+func TestComplexSelector_matches(t *testing.T) {
+	t.Parallel()
+
+	doc := parseHTML(`<html><body>
+		<nav id="top"><a class="link active" href="/home">Home</a><a class="link" href="https://x.com/about" lang="en-US">About</a></nav>
+		<main><h1>Title</h1><p class="lead">Lead</p><p>Text</p><input type="TEXT" disabled></main>
+	</body></html>`)
+
+	tests := []struct {
+		selector string
+		want     bool
+	}{
+		{"a", true},
+		{"button", false},
+		{"*", true},
+		{"#top", true},
+		{"#bottom", false},
+		{".link.active", true},
+		{".link.missing", false},
+		{"nav a", true},
+		{"body a", true},
+		{"main a", false},
+		{"nav > a", true},
+		{"body > a", false},
+		{"h1 + p", true},
+		{"h1 + .lead + p", true},
+		{"h1 + input", false},
+		{"h1 ~ input", true},
+		{"input ~ h1", false},
+		{"[href]", true},
+		{"[target]", false},
+		{`[href="/home"]`, true},
+		{`a[href^="https:"]`, true},
+		{`a[href$=".com"]`, false},
+		{`a[href*="x.com"]`, true},
+		{"[class~=active]", true},
+		{"[lang|=en]", true},
+		{`[type="text"]`, false},
+		{`[type="text" i]`, true},
+		{"a:hover", true},
+		{"p::first-line", true},
+		{"a:first-child.active", true},
+		{"a:last-child.active", false},
+		{"h1:only-of-type", true},
+		{"p:first-of-type.lead", true},
+		{"p:last-of-type.lead", false},
+		{"html:root", true},
+		{"body:root", false},
+		{"input:empty", true},
+		{":is(nav, footer) > a", true},
+		{":where(footer, aside) a", false},
+		{"a:not(.active)", true},
+		{"A.LINK", false},
+		{"A.link", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.selector, func(t *testing.T) {
+			t.Parallel()
+
+			list, ok := parseSelectorList(tt.selector)
+			if !ok {
+				t.Fatalf("parseSelectorList(%q) ok = false, want true", tt.selector)
+			}
+			got := false
+			doc.walk(func(e *htmlElement) {
+				got = got || matchesAny(list, e)
+			})
+			if got != tt.want {
+				t.Errorf("selector %q matches = %v, want %v", tt.selector, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseSelectorList_unsupported(t *testing.T) {
+	t.Parallel()
+
+	for _, selector := range []string{
+		"",
+		"& .child",
+		"> a",
+		"a >",
+		"svg|rect",
+		"a[",
+		"[=x]",
+		"[x=]",
+		"[x=y z]",
+		"a, ",
+	} {
+		if _, ok := parseSelectorList(selector); ok {
+			t.Errorf("parseSelectorList(%q) ok = true, want false", selector)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package strata
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Split is a stylesheet split into one content-hashed file per layer, so
+// that changing one layer does not invalidate the cache for the others.
+type Split struct {
+	// Entry is the entry stylesheet: the layer order statement, an
+	// @import for each layer file in order, and any unlayered content.
+	Entry string
+
+	// Files maps the content-hashed name of each layer file, such as
+	// "components.buttons.1a2b3c4d5e6f7a8b.css", to its content.
+	Files map[string][]byte
+}
+
+// Split renders each layer to its own file and returns them with an entry
+// stylesheet importing them from baseURL:
+//
+//	@layer base, components;
+//	@import url("/css/base.1a2b3c4d5e6f7a8b.css") layer(base);
+//	@import url("/css/components.9f8e7d6c5b4a3f2e.css") layer(components);
+//
+// The header always lists every layer, so the order holds however the
+// imports load. External layers appear only in the header. Relative URLs
+// in layer content resolve against the layer file, so serve the files
+// from the same directory as the entry or use an absolute Config.BaseURL.
+func (r *Result) Split(baseURL string) *Split {
+	s := &Split{Files: make(map[string][]byte)}
+
+	names := r.order
+	if names == nil {
+		names = layerNames(r.Layers)
+	}
+
+	var entry strings.Builder
+	if len(names) > 0 {
+		writeOrderStatement(&entry, names)
+	}
+	for _, l := range r.Layers {
+		if l.External {
+			continue
+		}
+		name := l.Name + "." + contentHash([]byte(l.Content)) + cssExtension
+		s.Files[name] = []byte(l.Content)
+		fmt.Fprintf(&entry, "@import url(%q) layer(%s);\n", joinURL(baseURL, name), l.Name)
+	}
+	entry.WriteString(r.Unlayered)
+	s.Entry = entry.String()
+
+	return s
+}
+
+// WriteFiles writes Split.Files to dir, which should be served at the
+// base URL passed to Result.Split.
+func (s *Split) WriteFiles(dir string) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("write layer files: %w", err)
+	}
+	for name, data := range s.Files {
+		if err := os.WriteFile(filepath.Join(dir, name), data, 0o644); err != nil {
+			return fmt.Errorf("write layer file %s: %w", name, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package strata
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestResult_Split(t *testing.T) {
+	t.Parallel()
+
+	r := &Result{
+		Layers: []Layer{
+			{Name: "reset", External: true},
+			{Name: "base", Content: "body { margin: 0; }\n"},
+			{Name: "components.buttons", Content: ".btn { color: red; }\n"},
+		},
+		Unlayered: ".debug { outline: 1px solid; }\n",
+	}
+
+	got := r.Split("/css/")
+
+	base := "base." + contentHash([]byte("body { margin: 0; }\n")) + ".css"
+	buttons := "components.buttons." + contentHash([]byte(".btn { color: red; }\n")) + ".css"
+	wantEntry := "@layer reset, base, components.buttons;\n" +
+		`@import url("/css/` + base + `") layer(base);` + "\n" +
+		`@import url("/css/` + buttons + `") layer(components.buttons);` + "\n" +
+		".debug { outline: 1px solid; }\n"
+	if got.Entry != wantEntry {
+		t.Errorf("Split().Entry =\n%s\nwant:\n%s", got.Entry, wantEntry)
+	}
+
+	if len(got.Files) != 2 || string(got.Files[base]) != "body { margin: 0; }\n" ||
+		string(got.Files[buttons]) != ".btn { color: red; }\n" {
+		t.Errorf("Split().Files keys = %v, want %s and %s", mapKeys(got.Files), base, buttons)
+	}
+}
+
+func TestResult_Split_empty(t *testing.T) {
+	t.Parallel()
+
+	got := (&Result{}).Split("")
+	if got.Entry != "" || len(got.Files) != 0 {
+		t.Errorf("Split() = %+v, want empty", got)
+	}
+}
+
+func TestSplit_WriteFiles(t *testing.T) {
+	t.Parallel()
+
+	dir := filepath.Join(t.TempDir(), "css")
+	s := &Split{Files: map[string][]byte{"base.123.css": []byte("body {}\n")}}
+
+	if err := s.WriteFiles(dir); err != nil {
+		t.Fatalf("Split.WriteFiles() error = %v, want nil", err)
+	}
+
+	got, err := os.ReadFile(filepath.Join(dir, "base.123.css"))
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+	if string(got) != "body {}\n" {
+		t.Errorf("layer file = %q, want %q", got, "body {}\n")
+	}
+}
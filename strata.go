@@ -4,26 +4,210 @@ package strata
 import (
 	"bytes"
 	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
 	"encoding/hex"
 	"fmt"
 	"io/fs"
 	"path"
+	"slices"
 	"sort"
 	"strings"
+	"text/template"
 )
 
-const cssExtension = ".css"
+const (
+	cssExtension      = ".css"
+	templateExtension = ".css.tmpl"
+	tokensExtension   = ".tokens.json"
+)
+
+// sourceExtensions lists the file suffixes Build reads, longest first so
+// that compound suffixes match before ".css".
+var sourceExtensions = []string{tokensExtension, templateExtension, cssExtension}
+
+// sourceExtension returns the source suffix of filePath, or an empty
+// string when Build does not read the file.
+func sourceExtension(filePath string) string {
+	for _, ext := range sourceExtensions {
+		if strings.HasSuffix(filePath, ext) {
+			return ext
+		}
+	}
+	return ""
+}
+
+// baseName returns the file's name without its source suffix
+// (e.g., "brand.css.tmpl" -> "brand").
+func baseName(filePath string) string {
+	base := path.Base(filePath)
+	if ext := sourceExtension(base); ext != "" {
+		return strings.TrimSuffix(base, ext)
+	}
+	return strings.TrimSuffix(base, path.Ext(base))
+}
 
 // Source represents a CSS source directory to build from.
 type Source struct {
 	// FS is the filesystem to read from.
 	// The filesystem should be rooted at the directory containing CSS files.
 	// Use fs.Sub() to create a sub-filesystem if needed.
+	// A nil FS with Layer set declares an external layer (see ExternalLayer).
 	FS fs.FS
 
 	// Prefix is an optional namespace to prepend to all layer names.
 	// If set, layer names will be "prefix.layername" instead of "layername".
 	Prefix string
+
+	// Mode controls how file paths map to layer names.
+	// The zero value is LayerByDirectory.
+	Mode LayerMode
+
+	// MaxDepth limits the number of layer name segments below Prefix.
+	// Deeper layers collapse into their ancestor at MaxDepth, with files
+	// concatenated in path order. Zero means no limit.
+	MaxDepth int
+
+	// Order controls how this source's layers are ordered.
+	// The zero value is OrderByDepth.
+	Order LayerOrder
+
+	// Unlayered emits this source's files without any @layer wrapper, after
+	// all layer blocks, so they take precedence over every layered rule.
+	// Unlayered files are excluded from the order statement.
+	Unlayered bool
+
+	// Layer, if set, places every file of this source into the single named
+	// layer, in walk order, ignoring directory structure. Prefix still
+	// applies. Useful for vendored CSS whose layout is meaningless.
+	Layer string
+
+	// Transformers process each file of this source, in order, before the
+	// Config's Transformers run.
+	Transformers []Transformer
+
+	// ScopeClasses rewrites class selectors in this source's layers to
+	// names unique to each layer, such as .title -> .card_title_4f1c2a, so
+	// components cannot collide. Result.Classes maps the original names to
+	// the scoped ones for use in templates.
+	ScopeClasses bool
+
+	// ScopeAttribute, if set, wraps each layer's content in an @scope rule
+	// rooted at elements whose attribute equals the last segment of the
+	// layer name. With ScopeAttribute "data-component", components/card/
+	// becomes @scope ([data-component="card"]) inside @layer components.card,
+	// so its styles cannot leak outside that markup.
+	ScopeAttribute string
+
+	// URL is the URL path at which this source's files are served,
+	// relative to Config.BaseURL unless it starts with "/". It is used when
+	// Config.RewriteURLs is set, and by OutputImports and Config.Handler
+	// to serve the original files.
+	URL string
+}
+
+// LayerOrder controls the order in which a Source's layers are declared.
+type LayerOrder int
+
+const (
+	// OrderByDepth declares shallow layers before deep ones, then sorts
+	// alphabetically: base, components, base.elements, components.buttons.
+	OrderByDepth LayerOrder = iota
+
+	// OrderByTree walks the layer tree depth-first, declaring each parent
+	// before its children: base, base.elements, components, components.buttons.
+	OrderByTree
+)
+
+// LayerMode controls how a Source maps file paths to layer names.
+type LayerMode int
+
+const (
+	// LayerByDirectory merges every file in a directory into one layer.
+	// Root files become individual layers.
+	LayerByDirectory LayerMode = iota
+
+	// LayerByFile gives every file its own layer, named after its directory
+	// and filename (e.g., components/card.css -> components.card).
+	LayerByFile
+
+	// LayerHybrid behaves like LayerByFile, except that root files map to
+	// the root layer: the Source Prefix, or their own layer without one.
+	LayerHybrid
+)
+
+// indexFiles are the base names of files whose content belongs to their
+// directory's own layer, in every LayerMode (e.g., _layer.css, index.css).
+// At the root, that is the Source Prefix layer; without a Prefix, a root
+// index file is an ordinary file named after itself. An @layer statement
+// in an index file orders the directory's child layers (see
+// indexChildOrder).
+var indexFiles = []string{"_layer", "index"}
+
+// isIndexFile reports whether filePath names a directory's index file.
+func isIndexFile(filePath string) bool {
+	return slices.Contains(indexFiles, baseName(filePath))
+}
+
+// fileSortKey returns the key that orders files within a source.
+// Files sort alphabetically by path, except that index files sort before
+// every other entry in their directory.
+func fileSortKey(filePath string) string {
+	if !isIndexFile(filePath) {
+		return filePath
+	}
+	dir := path.Dir(filePath)
+	if dir == "." {
+		return "\x00" + path.Base(filePath)
+	}
+	return dir + "/\x00" + path.Base(filePath)
+}
+
+// layerName returns the full layer name for a file in this source,
+// including any Prefix.
+func (s Source) layerName(filePath string) string {
+	if s.Layer != "" {
+		if s.Prefix == "" {
+			return s.Layer
+		}
+		return s.Prefix + "." + s.Layer
+	}
+
+	var name string
+	switch {
+	case s.isParentIndexFile(filePath):
+		name = dirToLayerName(path.Dir(filePath))
+	case s.Mode == LayerByFile:
+		name = fileToLayerName(filePath)
+	case s.Mode == LayerHybrid:
+		if path.Dir(filePath) != "." {
+			name = fileToLayerName(filePath)
+		}
+	default:
+		name = pathToLayerName(filePath)
+	}
+
+	name = truncateLayerName(name, s.MaxDepth)
+
+	switch {
+	case s.Prefix == "":
+		if name == "" {
+			// Root layer without a Prefix: fall back to the filename
+			return fileToLayerName(filePath)
+		}
+		return name
+	case name == "":
+		return s.Prefix
+	default:
+		return s.Prefix + "." + name
+	}
+}
+
+// isParentIndexFile reports whether filePath is an index file holding its
+// directory's own layer. A root index file only does so under a Prefix;
+// without one there is no parent layer, so it is an ordinary file.
+func (s Source) isParentIndexFile(filePath string) bool {
+	return isIndexFile(filePath) && (path.Dir(filePath) != "." || s.Prefix != "")
 }
 
 // pathToLayerName converts a file path to its CSS layer name.
@@ -42,11 +226,52 @@ func pathToLayerName(filePath string) string {
 
 	// Root file: use filename without extension
 	if dirPart == "." {
-		return strings.TrimSuffix(path.Base(filePath), path.Ext(filePath))
+		return baseName(filePath)
 	}
 
 	// Nested path: replace "/" with "." to form layer name
-	return strings.ReplaceAll(dirPart, "/", ".")
+	return dirToLayerName(dirPart)
+}
+
+// fileToLayerName converts a file path to a per-file layer name.
+//
+// Examples:
+//   - fileToLayerName("reset.css") -> "reset"
+//   - fileToLayerName("components/card.css") -> "components.card"
+//   - fileToLayerName("base/elements/btn.css") -> "base.elements.btn"
+func fileToLayerName(filePath string) string {
+	base := baseName(filePath)
+	dirName := dirToLayerName(path.Dir(filePath))
+	if dirName == "" {
+		return base
+	}
+	return dirName + "." + base
+}
+
+// truncateLayerName keeps at most maxDepth dot-separated segments of name.
+// A maxDepth of zero or less leaves name unchanged.
+//
+// Examples:
+//   - truncateLayerName("settings.billing.invoices", 2) -> "settings.billing"
+//   - truncateLayerName("settings", 2) -> "settings"
+func truncateLayerName(name string, maxDepth int) string {
+	if maxDepth <= 0 {
+		return name
+	}
+	segments := strings.Split(name, ".")
+	if len(segments) <= maxDepth {
+		return name
+	}
+	return strings.Join(segments[:maxDepth], ".")
+}
+
+// dirToLayerName converts a directory path to a layer name.
+// The root directory "." converts to an empty name.
+func dirToLayerName(dir string) string {
+	if dir == "." {
+		return ""
+	}
+	return strings.ReplaceAll(dir, "/", ".")
 }
 
 // layer represents a CSS cascade layer being built.
@@ -54,6 +279,78 @@ type layer struct {
 	name    string
 	depth   int
 	content *bytes.Buffer
+
+	// unlayered marks content emitted outside any layer.
+	unlayered bool
+
+	// external marks a layer declared only in the order statement, whose
+	// content is loaded from elsewhere.
+	external bool
+
+	// files lists the URLs of the layer's source files, for OutputImports.
+	files []string
+}
+
+// ExternalLayer returns a Source that declares a content-less layer.
+//
+// The layer appears in the order statement at the Source's position but
+// produces no block. Use it to fix the precedence of layers whose content
+// arrives from other stylesheets, such as a CSS framework:
+//
+//	css, err := strata.Build(
+//	    strata.Source{FS: stylesFS},
+//	    strata.ExternalLayer("tw.utilities"),
+//	    strata.Source{FS: componentsFS, Prefix: "comp"},
+//	)
+//	// Output: @layer reset, tw.utilities, comp.button;
+func ExternalLayer(name string) Source {
+	return Source{Layer: name}
+}
+
+// sortLayers sorts layers in place according to order. Siblings named in
+// childOrder, from index file @layer statements, sort first and in the
+// listed order.
+func sortLayers(layers []*layer, order LayerOrder, childOrder []string) {
+	if order == OrderByTree {
+		sort.Slice(layers, func(i, j int) bool {
+			return compareLayerNames(layers[i].name, layers[j].name, childOrder) < 0
+		})
+		return
+	}
+
+	sort.Slice(layers, func(i, j int) bool {
+		if layers[i].depth != layers[j].depth {
+			return layers[i].depth < layers[j].depth
+		}
+		if len(childOrder) > 0 {
+			return compareLayerNames(layers[i].name, layers[j].name, childOrder) < 0
+		}
+		return layers[i].name < layers[j].name
+	})
+}
+
+// compareLayerNames compares layer names segment by segment. At the first
+// differing segment, siblings listed in childOrder come first, in listed
+// order; others compare alphabetically.
+func compareLayerNames(a, b string, childOrder []string) int {
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(as) && i < len(bs); i++ {
+		if as[i] == bs[i] {
+			continue
+		}
+		ai := slices.Index(childOrder, strings.Join(as[:i+1], "."))
+		bi := slices.Index(childOrder, strings.Join(bs[:i+1], "."))
+		switch {
+		case ai >= 0 && bi >= 0:
+			return ai - bi
+		case ai >= 0:
+			return -1
+		case bi >= 0:
+			return 1
+		}
+		return strings.Compare(as[i], bs[i])
+	}
+	return len(as) - len(bs)
 }
 
 // Build walks one or more source filesystems and returns CSS with @layer declarations.
@@ -63,6 +360,7 @@ type layer struct {
 //   - Root files (e.g., reset.css) become individual layers
 //   - Nested directories use dot notation (e.g., base/elements/ -> base.elements)
 //   - Optional Prefix prepends a namespace (e.g., Prefix: "comp" -> comp.button)
+//   - Mode selects per-directory, per-file, or hybrid layers (see LayerMode)
 //
 // Output format:
 //
@@ -70,115 +368,339 @@ type layer struct {
 //	@layer name1 { ... content ... }
 //	@layer name2 { ... content ... }
 //
-// Files within the same layer are concatenated in alphabetical order.
-// Within each source, layers are ordered depth-first (shallow before deep), then alphabetically.
+// Files ending in .css.tmpl are executed as text/template with the Config's
+// TemplateData and TemplateFuncs, and files ending in .tokens.json are
+// converted from W3C Design Tokens to custom properties on :root. Both are
+// then layered like .css files.
+//
+// Sources with Unlayered set are written after all layer blocks, without
+// any @layer wrapper.
+//
+// Files within the same layer are concatenated in alphabetical order, except
+// that index files (_layer.css, index.css) come first. An index file holds
+// the content of its directory's own layer in every mode, so a parent layer
+// such as components can carry styles alongside components/buttons/. A root
+// index file maps to the Prefix layer, or without a Prefix is an ordinary
+// file named after itself. A top-level @layer statement in an index file
+// orders the directory's child layers it names, ahead of their siblings.
+// Within each source, layers are ordered by depth (shallow before deep), then alphabetically,
+// unless the source's Order is OrderByTree (see LayerOrder).
 // Empty sources return an empty string (not an error).
 func Build(sources ...Source) (string, error) {
-	var allLayers []*layer
+	return Config{}.Build(sources...)
+}
 
-	// Process each source in order
-	for _, src := range sources {
-		layers := make(map[string]*layer)
-		var filePaths []string
+// Config holds build settings that apply across all sources.
+// The zero value builds the same output as the package-level Build.
+type Config struct {
+	// Output selects how layers are rendered.
+	// The zero value is OutputFlat.
+	Output Output
 
-		// Collect all CSS file paths from this source
-		err := fs.WalkDir(src.FS, ".", func(filePath string, d fs.DirEntry, err error) error {
-			if err != nil {
-				return err
-			}
-			if d.IsDir() {
-				return nil
-			}
-			if !strings.HasSuffix(filePath, cssExtension) {
-				return nil
-			}
-			filePaths = append(filePaths, filePath)
-			return nil
-		})
-		if err != nil {
-			return "", fmt.Errorf("walk filesystem: %w", err)
-		}
+	// FileLayers controls how Compile treats @layer rules inside source
+	// files. The zero value is AllowFileLayers.
+	FileLayers FileLayerPolicy
 
-		// Skip empty sources
-		if len(filePaths) == 0 {
-			continue
-		}
+	// Transformers process every file, in order, after the file's own
+	// Source.Transformers.
+	Transformers []Transformer
 
-		// Sort file paths for deterministic concatenation order
-		sort.Strings(filePaths)
+	// TemplateData is the data passed to every *.css.tmpl file.
+	TemplateData any
 
-		// Process each CSS file
-		for _, filePath := range filePaths {
-			content, err := fs.ReadFile(src.FS, filePath)
-			if err != nil {
-				return "", fmt.Errorf("read %s: %w", filePath, err)
-			}
+	// TemplateFuncs are added to every *.css.tmpl file's function map.
+	TemplateFuncs template.FuncMap
 
-			layerName := pathToLayerName(filePath)
+	// RewriteURLs rewrites relative url() and image-set() references so
+	// they still resolve once files are merged into one stylesheet. A
+	// reference in components/card/card.css to ./bg.png becomes
+	// BaseURL/<Source.URL>/card/bg.png.
+	RewriteURLs bool
 
-			// Apply prefix if specified
-			if src.Prefix != "" {
-				layerName = src.Prefix + "." + layerName
-			}
+	// BaseURL is the URL path under which source files are served, such as
+	// "/static". An empty BaseURL makes rewritten references relative to
+	// the built stylesheet.
+	BaseURL string
 
-			l, exists := layers[layerName]
-			if !exists {
-				l = &layer{
-					name:    layerName,
-					depth:   strings.Count(layerName, "."),
-					content: &bytes.Buffer{},
-				}
-				layers[layerName] = l
-			}
+	// HashAssets collects every local asset referenced by url() or
+	// image-set() (fonts, images, SVGs) into Result.Assets under a
+	// content-hashed name, such as bg.1a2b3c4d5e6f7a8b.png, and rewrites
+	// the references to BaseURL/<AssetDir>/<name>. A missing asset is an
+	// error naming the referencing file and line.
+	HashAssets bool
+
+	// AssetDir is the directory, relative to BaseURL, that hashed assets
+	// are written to.
+	AssetDir string
+
+	// InlineLimit, if positive, inlines local assets smaller than this many
+	// bytes as data: URIs: SVGs URL-encoded, others base64-encoded. Larger
+	// or missing assets are hashed with HashAssets, rewritten with
+	// RewriteURLs, and otherwise left as written to be served separately.
+	// References with a #fragment are never inlined.
+	InlineLimit int64
+}
+
+// Build walks the sources like the package-level Build, rendering layers
+// according to the config.
+func (c Config) Build(sources ...Source) (string, error) {
+	r, err := c.Compile(sources...)
+	if err != nil {
+		return "", err
+	}
+	return r.CSS(), nil
+}
+
+// BuildWithHash is like the package-level BuildWithHash, rendering layers
+// according to the config.
+func (c Config) BuildWithHash(sources ...Source) (css string, hash string, err error) {
+	css, err = c.Build(sources...)
+	if err != nil {
+		return "", "", err
+	}
+
+	return css, Hash(css), nil
+}
+
+// Hash returns the content hash used for cache busting: SHA-256, truncated
+// to 16 lowercase hexadecimal characters (8 bytes). Empty CSS returns an
+// empty hash.
+func Hash(css string) string {
+	if css == "" {
+		return ""
+	}
+
+	return contentHash([]byte(css))
+}
+
+// contentHash returns the truncated SHA-256 hash of data, as Hash does,
+// without special-casing empty data.
+func contentHash(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:8])
+}
+
+// Integrity returns the Subresource Integrity value for css, for use in a
+// <link> element's integrity attribute (e.g., "sha384-..."). Empty CSS
+// returns an empty value.
+func Integrity(css string) string {
+	if css == "" {
+		return ""
+	}
+
+	sum := sha512.Sum384([]byte(css))
+	return "sha384-" + base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// compiler holds the state of a single Compile call.
+type compiler struct {
+	config Config
+
+	// layers accumulates every source's layers in declaration order.
+	layers []*layer
+
+	// fileLayers records layers declared inside source files.
+	fileLayers []FileLayer
+
+	// warnings collects non-fatal problems found while compiling.
+	warnings []string
+
+	// classes maps layer names to their original and scoped class names.
+	classes map[string]map[string]string
+
+	// assets maps hashed asset paths to their content.
+	assets map[string][]byte
+
+	// childOrder lists the layer names ordered by index file @layer
+	// statements, in order. Listed layers sort before their unlisted
+	// siblings.
+	childOrder []string
+
+	// sourceLayers holds the layers produced by the source being compiled
+	// and their ancestors (see Source.layerTree).
+	sourceLayers map[string]bool
+}
+
+// readFile reads a source file and converts it to CSS according to its
+// suffix.
+func (c *compiler) readFile(s Source, filePath string) ([]byte, error) {
+	content, err := fs.ReadFile(s.FS, filePath)
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", filePath, err)
+	}
+
+	switch sourceExtension(filePath) {
+	case templateExtension:
+		return c.executeTemplate(filePath, content)
+	case tokensExtension:
+		return convertTokens(filePath, content)
+	default:
+		return content, nil
+	}
+}
+
+// processFile reads a source file and runs it through the per-file
+// pipeline, returning its layer name (empty for unlayered sources) and
+// processed content.
+func (c *compiler) processFile(s Source, filePath string) (string, []byte, error) {
+	content, err := c.readFile(s, filePath)
+	if err != nil {
+		return "", nil, err
+	}
+
+	var layerName string
+	if !s.Unlayered {
+		layerName = s.layerName(filePath)
+	}
 
-			l.content.Write(content)
-			l.content.WriteByte('\n')
+	var childOrder []string
+	if !s.Unlayered && s.Layer == "" && s.isParentIndexFile(filePath) {
+		childOrder = indexChildOrder(string(content), layerName, c.sourceLayers)
+		c.childOrder = append(c.childOrder, childOrder...)
+	}
+
+	// Imported files are served from their own paths, so their
+	// references already resolve
+	rewrite := c.config.RewriteURLs || c.config.HashAssets || c.config.InlineLimit > 0
+	if rewrite && c.config.Output != OutputImports {
+		content, err = c.rewriteFileURLs(s, filePath, content)
+		if err != nil {
+			return "", nil, err
 		}
+	}
+
+	content, err = c.transform(s, filePath, layerName, content)
+	if err != nil {
+		return "", nil, err
+	}
+
+	if s.ScopeClasses && layerName != "" {
+		content = []byte(c.scopeClasses(layerName, string(content)))
+	}
+
+	if err := c.checkFileLayers(filePath, layerName, string(content), childOrder); err != nil {
+		return "", nil, err
+	}
+
+	return layerName, content, nil
+}
 
-		// Convert map to slice and sort by depth then name
-		sortedLayers := make([]*layer, 0, len(layers))
-		for _, l := range layers {
-			sortedLayers = append(sortedLayers, l)
+// files returns the paths of the source's files that Build reads, in
+// concatenation order.
+func (s Source) files() ([]string, error) {
+	var filePaths []string
+	err := fs.WalkDir(s.FS, ".", func(filePath string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		if sourceExtension(filePath) == "" {
+			return nil
 		}
-		sort.Slice(sortedLayers, func(i, j int) bool {
-			if sortedLayers[i].depth != sortedLayers[j].depth {
-				return sortedLayers[i].depth < sortedLayers[j].depth
+		filePaths = append(filePaths, filePath)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("walk filesystem: %w", err)
+	}
+
+	// Sort file paths for deterministic concatenation order.
+	// A single named layer keeps walk order instead.
+	if s.Layer == "" {
+		sort.Slice(filePaths, func(i, j int) bool {
+			return fileSortKey(filePaths[i]) < fileSortKey(filePaths[j])
+		})
+	}
+	return filePaths, nil
+}
+
+// layerTree returns the names of the layers the source's files produce,
+// along with their ancestors, such as components for components.buttons.
+func (s Source) layerTree(filePaths []string) map[string]bool {
+	names := make(map[string]bool)
+	if s.Unlayered {
+		return names
+	}
+	for _, filePath := range filePaths {
+		name := s.layerName(filePath)
+		for {
+			names[name] = true
+			dot := strings.LastIndexByte(name, '.')
+			if dot < 0 {
+				break
 			}
-			return sortedLayers[i].name < sortedLayers[j].name
+			name = name[:dot]
+		}
+	}
+	return names
+}
+
+// addSource walks the source and appends its layers in declaration order.
+func (c *compiler) addSource(s Source) error {
+	// A source without a filesystem only declares its layer
+	if s.FS == nil && s.Layer != "" {
+		name := s.layerName("")
+		c.layers = append(c.layers, &layer{
+			name:     name,
+			depth:    strings.Count(name, "."),
+			content:  &bytes.Buffer{},
+			external: true,
 		})
+		return nil
+	}
 
-		// Append this source's layers to the final list
-		allLayers = append(allLayers, sortedLayers...)
+	filePaths, err := s.files()
+	if err != nil {
+		return err
 	}
 
-	// Handle empty result
-	if len(allLayers) == 0 {
-		return "", nil
+	// Skip empty sources
+	if len(filePaths) == 0 {
+		return nil
 	}
 
-	// Build output
-	var out bytes.Buffer
+	layers := make(map[string]*layer)
+	c.sourceLayers = s.layerTree(filePaths)
+
+	// Process each CSS file
+	for _, filePath := range filePaths {
+		layerName, content, err := c.processFile(s, filePath)
+		if err != nil {
+			return err
+		}
 
-	// Write layer declaration header
-	out.WriteString("@layer ")
-	for i, l := range allLayers {
-		if i > 0 {
-			out.WriteString(", ")
+		l, exists := layers[layerName]
+		if !exists {
+			l = &layer{
+				name:      layerName,
+				depth:     strings.Count(layerName, "."),
+				content:   &bytes.Buffer{},
+				unlayered: s.Unlayered,
+			}
+			layers[layerName] = l
 		}
-		out.WriteString(l.name)
+
+		l.content.Write(content)
+		l.content.WriteByte('\n')
+		l.files = append(l.files, joinURL(c.config.BaseURL, s.URL, filePath))
 	}
-	out.WriteString(";\n")
 
-	// Write each layer block
-	for _, l := range allLayers {
-		out.WriteString("@layer ")
-		out.WriteString(l.name)
-		out.WriteString(" {\n")
-		out.Write(l.content.Bytes())
-		out.WriteString("}\n")
+	// Convert map to slice and sort according to the source's order
+	sortedLayers := make([]*layer, 0, len(layers))
+	for _, l := range layers {
+		if s.ScopeAttribute != "" && !l.unlayered {
+			wrapInScope(l, s.ScopeAttribute)
+		}
+		sortedLayers = append(sortedLayers, l)
 	}
+	sortLayers(sortedLayers, s.Order, c.childOrder)
+
+	// Append this source's layers to the final list
+	c.layers = append(c.layers, sortedLayers...)
 
-	return out.String(), nil
+	return nil
 }
 
 // BuildWithHash returns the built CSS and a content hash for cache busting.
@@ -198,17 +720,5 @@ func Build(sources ...Source) (string, error) {
 //	// Use hash in filename: styles.{hash}.css
 //	fmt.Printf("<link rel=\"stylesheet\" href=\"/static/styles.%s.css\">\n", hash)
 func BuildWithHash(sources ...Source) (css string, hash string, err error) {
-	css, err = Build(sources...)
-	if err != nil {
-		return "", "", err
-	}
-
-	if css == "" {
-		return "", "", nil
-	}
-
-	sum := sha256.Sum256([]byte(css))
-	hash = hex.EncodeToString(sum[:8])
-
-	return css, hash, nil
+	return Config{}.BuildWithHash(sources...)
 }
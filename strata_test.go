@@ -4,6 +4,7 @@ import (
 	"errors"
 	"io/fs"
 	"regexp"
+	"slices"
 	"strings"
 	"testing"
 	"testing/fstest"
@@ -56,6 +57,11 @@ func TestPathToLayerName(t *testing.T) {
 			givePath:      "a/b.css",
 			wantLayerName: "a",
 		},
+		{
+			name:          "template_root_file",
+			givePath:      "brand.css.tmpl",
+			wantLayerName: "brand",
+		},
 		{
 			name:          "hyphen_in_name",
 			givePath:      "my-layer/file.css",
@@ -685,3 +691,625 @@ func TestBuild_sub_filesystems(t *testing.T) {
 		seen[layer] = true
 	}
 }
+
+func TestSource_layerName(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name          string
+		giveMode      LayerMode
+		givePrefix    string
+		givePath      string
+		wantLayerName string
+	}{
+		// Directory mode (default)
+		{
+			name:          "directory_root_file",
+			giveMode:      LayerByDirectory,
+			givePath:      "reset.css",
+			wantLayerName: "reset",
+		},
+		{
+			name:          "directory_nested_file",
+			giveMode:      LayerByDirectory,
+			givePath:      "components/card.css",
+			wantLayerName: "components",
+		},
+		// File mode
+		{
+			name:          "file_root_file",
+			giveMode:      LayerByFile,
+			givePath:      "reset.css",
+			wantLayerName: "reset",
+		},
+		{
+			name:          "file_nested_file",
+			giveMode:      LayerByFile,
+			givePath:      "components/card.css",
+			wantLayerName: "components.card",
+		},
+		{
+			name:          "file_deeply_nested",
+			giveMode:      LayerByFile,
+			givePath:      "base/elements/btn.css",
+			wantLayerName: "base.elements.btn",
+		},
+		{
+			name:          "file_index_maps_to_directory",
+			giveMode:      LayerByFile,
+			givePath:      "components/index.css",
+			wantLayerName: "components",
+		},
+		{
+			name:          "file_layer_file_maps_to_directory",
+			giveMode:      LayerByFile,
+			givePath:      "components/_layer.css",
+			wantLayerName: "components",
+		},
+		{
+			name:          "file_with_prefix",
+			giveMode:      LayerByFile,
+			givePrefix:    "comp",
+			givePath:      "button.css",
+			wantLayerName: "comp.button",
+		},
+		// Hybrid mode
+		{
+			name:          "hybrid_nested_file",
+			giveMode:      LayerHybrid,
+			givePath:      "components/card.css",
+			wantLayerName: "components.card",
+		},
+		{
+			name:          "hybrid_index_maps_to_directory",
+			giveMode:      LayerHybrid,
+			givePath:      "components/index.css",
+			wantLayerName: "components",
+		},
+		{
+			name:          "hybrid_root_file_without_prefix",
+			giveMode:      LayerHybrid,
+			givePath:      "reset.css",
+			wantLayerName: "reset",
+		},
+		{
+			name:          "hybrid_root_file_with_prefix",
+			giveMode:      LayerHybrid,
+			givePrefix:    "comp",
+			givePath:      "shared.css",
+			wantLayerName: "comp",
+		},
+		{
+			name:          "directory_root_layer_file_with_prefix",
+			giveMode:      LayerByDirectory,
+			givePrefix:    "comp",
+			givePath:      "_layer.css",
+			wantLayerName: "comp",
+		},
+		{
+			name:          "directory_root_index_without_prefix",
+			giveMode:      LayerByDirectory,
+			givePath:      "index.css",
+			wantLayerName: "index",
+		},
+		{
+			name:          "hybrid_root_layer_file_without_prefix",
+			giveMode:      LayerHybrid,
+			givePath:      "_layer.css",
+			wantLayerName: "_layer",
+		},
+		{
+			name:          "hybrid_root_index_with_prefix",
+			giveMode:      LayerHybrid,
+			givePrefix:    "comp",
+			givePath:      "index.css",
+			wantLayerName: "comp",
+		},
+		{
+			name:          "hybrid_nested_with_prefix",
+			giveMode:      LayerHybrid,
+			givePrefix:    "comp",
+			givePath:      "card/title.css",
+			wantLayerName: "comp.card.title",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			src := Source{Prefix: tt.givePrefix, Mode: tt.giveMode}
+			if got := src.layerName(tt.givePath); got != tt.wantLayerName {
+				t.Errorf("Source.layerName(%q) = %q, want %q",
+					tt.givePath, got, tt.wantLayerName)
+			}
+		})
+	}
+}
+
+func TestBuild_layer_modes(t *testing.T) {
+	t.Parallel()
+
+	testFS := fstest.MapFS{
+		"reset.css":             {Data: []byte("/* reset */")},
+		"components/index.css":  {Data: []byte("/* index */")},
+		"components/button.css": {Data: []byte("/* button */")},
+		"components/card.css":   {Data: []byte("/* card */")},
+	}
+
+	tests := []struct {
+		name          string
+		giveMode      LayerMode
+		wantLayerDecl string
+	}{
+		{
+			name:          "directory",
+			giveMode:      LayerByDirectory,
+			wantLayerDecl: "@layer components, reset;",
+		},
+		{
+			name:          "file",
+			giveMode:      LayerByFile,
+			wantLayerDecl: "@layer components, reset, components.button, components.card;",
+		},
+		{
+			name:          "hybrid",
+			giveMode:      LayerHybrid,
+			wantLayerDecl: "@layer components, reset, components.button, components.card;",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := Build(Source{FS: testFS, Mode: tt.giveMode})
+			if err != nil {
+				t.Fatalf("Build() error = %v, want nil", err)
+			}
+
+			if !strings.HasPrefix(got, tt.wantLayerDecl) {
+				t.Errorf("Build() layer declaration = %q, want %q",
+					strings.SplitN(got, "\n", 2)[0], tt.wantLayerDecl)
+			}
+		})
+	}
+}
+
+func TestBuild_index_files(t *testing.T) {
+	t.Parallel()
+
+	testFS := fstest.MapFS{
+		"components/alert.css":          {Data: []byte("/* alert */")},
+		"components/index.css":          {Data: []byte("/* index */")},
+		"components/_layer.css":         {Data: []byte("/* layer */")},
+		"components/buttons/button.css": {Data: []byte("/* button */")},
+	}
+
+	got, err := Build(Source{FS: testFS})
+	if err != nil {
+		t.Fatalf("Build() error = %v, want nil", err)
+	}
+
+	wantLayerDecl := "@layer components, components.buttons;"
+	if !strings.HasPrefix(got, wantLayerDecl) {
+		t.Errorf("Build() layer declaration = %q, want %q",
+			strings.SplitN(got, "\n", 2)[0], wantLayerDecl)
+	}
+
+	// Index files come first in their layer, _layer.css before index.css
+	layerIdx := strings.Index(got, "/* layer */")
+	indexIdx := strings.Index(got, "/* index */")
+	alertIdx := strings.Index(got, "/* alert */")
+	if layerIdx == -1 || indexIdx == -1 || alertIdx == -1 {
+		t.Fatalf("Build() missing expected content, got: %s", got)
+	}
+	if layerIdx > indexIdx || indexIdx > alertIdx {
+		t.Errorf("Build() index files should precede other files, got: %s", got)
+	}
+}
+
+func TestBuild_root_index_file(t *testing.T) {
+	t.Parallel()
+
+	testFS := fstest.MapFS{
+		"_layer.css": {Data: []byte("/* root */")},
+		"button.css": {Data: []byte("/* button */")},
+		"card/a.css": {Data: []byte("/* card */")},
+	}
+
+	got, err := Build(Source{FS: testFS, Prefix: "comp"})
+	if err != nil {
+		t.Fatalf("Build() error = %v, want nil", err)
+	}
+
+	wantLayerDecl := "@layer comp, comp.button, comp.card;"
+	if !strings.HasPrefix(got, wantLayerDecl) {
+		t.Errorf("Build() layer declaration = %q, want %q",
+			strings.SplitN(got, "\n", 2)[0], wantLayerDecl)
+	}
+
+	// Without a Prefix there is no parent layer: the file is its own layer
+	got, err = Build(Source{FS: testFS})
+	if err != nil {
+		t.Fatalf("Build() without Prefix error = %v, want nil", err)
+	}
+	wantLayerDecl = "@layer _layer, button, card;"
+	if !strings.HasPrefix(got, wantLayerDecl) {
+		t.Errorf("Build() without Prefix layer declaration = %q, want %q",
+			strings.SplitN(got, "\n", 2)[0], wantLayerDecl)
+	}
+}
+
+func TestBuild_index_file_child_order(t *testing.T) {
+	t.Parallel()
+
+	testFS := fstest.MapFS{
+		"reset.css":                     {Data: []byte("/* reset */")},
+		"components/_layer.css":         {Data: []byte("@layer modal, card;\n/* components */")},
+		"components/alert/alert.css":    {Data: []byte("/* alert */")},
+		"components/card/card.css":      {Data: []byte("/* card */")},
+		"components/modal/modal.css":    {Data: []byte("/* modal */")},
+		"components/modal/_layer.css":   {Data: []byte("@layer footer, header;")},
+		"components/modal/header/h.css": {Data: []byte("/* header */")},
+		"components/modal/footer/f.css": {Data: []byte("/* footer */")},
+	}
+
+	tests := []struct {
+		name          string
+		giveOrder     LayerOrder
+		wantLayerDecl string
+	}{
+		{
+			name:      "by_depth",
+			giveOrder: OrderByDepth,
+			wantLayerDecl: "@layer components, reset, components.modal, components.card, components.alert, " +
+				"components.modal.footer, components.modal.header;",
+		},
+		{
+			name:      "by_tree",
+			giveOrder: OrderByTree,
+			wantLayerDecl: "@layer components, components.modal, components.modal.footer, components.modal.header, " +
+				"components.card, components.alert, reset;",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			res, err := Config{FileLayers: RejectFileLayers}.Compile(Source{FS: testFS, Order: tt.giveOrder})
+			if err != nil {
+				t.Fatalf("Config.Compile() error = %v, want nil", err)
+			}
+
+			got := res.CSS()
+			if !strings.HasPrefix(got, tt.wantLayerDecl+"\n") {
+				t.Errorf("Compile() layer declaration = %q, want %q",
+					strings.SplitN(got, "\n", 2)[0], tt.wantLayerDecl)
+			}
+			for _, stmt := range []string{"@layer modal, card;", "@layer footer, header;"} {
+				if !strings.Contains(got, stmt) {
+					t.Errorf("Compile() dropped index file statement %q, got:\n%s", stmt, got)
+				}
+			}
+		})
+	}
+}
+
+func TestBuild_index_file_sub_layer_order(t *testing.T) {
+	t.Parallel()
+
+	// Names that are not child layers of the source order the file's own
+	// @layer blocks, as in any other file
+	testFS := fstest.MapFS{
+		"components/index.css": {Data: []byte(
+			"@layer card, state, base;\n" +
+				"@layer base { .a { color: red; } }\n" +
+				"@layer state { .a { color: blue; } }\n",
+		)},
+		"components/card/card.css": {Data: []byte("/* card */")},
+	}
+
+	res, err := Compile(Source{FS: testFS})
+	if err != nil {
+		t.Fatalf("Compile() error = %v, want nil", err)
+	}
+
+	got := res.CSS()
+	if want := "@layer components, components.card;\n"; !strings.HasPrefix(got, want) {
+		t.Errorf("Compile() layer declaration = %q, want %q", strings.SplitN(got, "\n", 2)[0], want)
+	}
+	if !strings.Contains(got, "@layer card, state, base;") {
+		t.Errorf("Compile() dropped the index file statement, got:\n%s", got)
+	}
+
+	var names []string
+	for _, fl := range res.FileLayers {
+		names = append(names, fl.Name)
+	}
+	if want := []string{"components.state", "components.base"}; !slices.Equal(names, want) {
+		t.Errorf("Result.FileLayers = %v, want %v", names, want)
+	}
+}
+
+func TestTruncateLayerName(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name         string
+		giveName     string
+		giveMaxDepth int
+		wantName     string
+	}{
+		{
+			name:         "no_limit",
+			giveName:     "settings.billing.invoices",
+			giveMaxDepth: 0,
+			wantName:     "settings.billing.invoices",
+		},
+		{
+			name:         "negative_is_no_limit",
+			giveName:     "settings.billing",
+			giveMaxDepth: -1,
+			wantName:     "settings.billing",
+		},
+		{
+			name:         "collapses_deeper",
+			giveName:     "settings.billing.invoices.detail",
+			giveMaxDepth: 2,
+			wantName:     "settings.billing",
+		},
+		{
+			name:         "at_limit",
+			giveName:     "settings.billing",
+			giveMaxDepth: 2,
+			wantName:     "settings.billing",
+		},
+		{
+			name:         "shallower",
+			giveName:     "settings",
+			giveMaxDepth: 2,
+			wantName:     "settings",
+		},
+		{
+			name:         "empty_root_layer",
+			giveName:     "",
+			giveMaxDepth: 1,
+			wantName:     "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got := truncateLayerName(tt.giveName, tt.giveMaxDepth)
+			if got != tt.wantName {
+				t.Errorf("truncateLayerName(%q, %d) = %q, want %q",
+					tt.giveName, tt.giveMaxDepth, got, tt.wantName)
+			}
+		})
+	}
+}
+
+func TestBuild_max_depth(t *testing.T) {
+	t.Parallel()
+
+	testFS := fstest.MapFS{
+		"home.css":                                 {Data: []byte("/* home */")},
+		"settings/profile.css":                     {Data: []byte("/* settings */")},
+		"settings/billing/billing.css":             {Data: []byte("/* billing */")},
+		"settings/billing/invoices/list.css":       {Data: []byte("/* list */")},
+		"settings/billing/invoices/detail/one.css": {Data: []byte("/* detail */")},
+	}
+
+	got, err := Build(Source{FS: testFS, Prefix: "page", MaxDepth: 2})
+	if err != nil {
+		t.Fatalf("Build() error = %v, want nil", err)
+	}
+
+	wantLayerDecl := "@layer page.home, page.settings, page.settings.billing;"
+	if !strings.HasPrefix(got, wantLayerDecl) {
+		t.Errorf("Build() layer declaration = %q, want %q",
+			strings.SplitN(got, "\n", 2)[0], wantLayerDecl)
+	}
+
+	// Collapsed files are concatenated in path order
+	billingIdx := strings.Index(got, "/* billing */")
+	detailIdx := strings.Index(got, "/* detail */")
+	listIdx := strings.Index(got, "/* list */")
+	if billingIdx == -1 || detailIdx == -1 || listIdx == -1 {
+		t.Fatalf("Build() missing expected content, got: %s", got)
+	}
+	if billingIdx > detailIdx || detailIdx > listIdx {
+		t.Errorf("Build() collapsed content should follow path order, got: %s", got)
+	}
+}
+
+func TestBuild_layer_order(t *testing.T) {
+	t.Parallel()
+
+	testFS := fstest.MapFS{
+		"base/file.css":              {Data: []byte("a")},
+		"base/elements/btn.css":      {Data: []byte("b")},
+		"base-extra/file.css":        {Data: []byte("c")},
+		"components/card.css":        {Data: []byte("d")},
+		"components/buttons/btn.css": {Data: []byte("e")},
+	}
+
+	tests := []struct {
+		name          string
+		giveOrder     LayerOrder
+		wantLayerDecl string
+	}{
+		{
+			name:          "by_depth",
+			giveOrder:     OrderByDepth,
+			wantLayerDecl: "@layer base, base-extra, components, base.elements, components.buttons;",
+		},
+		{
+			name:          "by_tree",
+			giveOrder:     OrderByTree,
+			wantLayerDecl: "@layer base, base.elements, base-extra, components, components.buttons;",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := Build(Source{FS: testFS, Order: tt.giveOrder})
+			if err != nil {
+				t.Fatalf("Build() error = %v, want nil", err)
+			}
+
+			if !strings.HasPrefix(got, tt.wantLayerDecl) {
+				t.Errorf("Build() layer declaration = %q, want %q",
+					strings.SplitN(got, "\n", 2)[0], tt.wantLayerDecl)
+			}
+		})
+	}
+}
+
+func TestBuild_unlayered(t *testing.T) {
+	t.Parallel()
+
+	stylesFS := fstest.MapFS{
+		"reset.css": {Data: []byte("/* reset */")},
+	}
+	overridesFS := fstest.MapFS{
+		"widget.css":      {Data: []byte("/* widget */")},
+		"print/hacks.css": {Data: []byte("/* print */")},
+	}
+	componentsFS := fstest.MapFS{
+		"button.css": {Data: []byte("/* button */")},
+	}
+
+	got, err := Build(
+		Source{FS: stylesFS},
+		Source{FS: overridesFS, Unlayered: true},
+		Source{FS: componentsFS, Prefix: "comp"},
+	)
+	if err != nil {
+		t.Fatalf("Build() error = %v, want nil", err)
+	}
+
+	want := "@layer reset, comp.button;\n" +
+		"@layer reset {\n/* reset */\n}\n" +
+		"@layer comp.button {\n/* button */\n}\n" +
+		"/* print */\n" +
+		"/* widget */\n"
+	if got != want {
+		t.Errorf("Build() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestBuild_unlayered_only(t *testing.T) {
+	t.Parallel()
+
+	testFS := fstest.MapFS{
+		"widget.css": {Data: []byte("/* widget */")},
+	}
+
+	got, err := Build(Source{FS: testFS, Unlayered: true})
+	if err != nil {
+		t.Fatalf("Build() error = %v, want nil", err)
+	}
+
+	want := "/* widget */\n"
+	if got != want {
+		t.Errorf("Build() = %q, want %q", got, want)
+	}
+}
+
+func TestBuild_single_layer(t *testing.T) {
+	t.Parallel()
+
+	vendorFS := fstest.MapFS{
+		"normalize.css":              {Data: []byte("/* normalize */")},
+		"datepicker/index.css":       {Data: []byte("/* index */")},
+		"datepicker/theme/dark.css":  {Data: []byte("/* dark */")},
+		"datepicker/calendar.css":    {Data: []byte("/* calendar */")},
+		"datepicker/theme/light.css": {Data: []byte("/* light */")},
+	}
+
+	tests := []struct {
+		name       string
+		giveSource Source
+		wantName   string
+	}{
+		{
+			name:       "named_layer",
+			giveSource: Source{FS: vendorFS, Layer: "vendor"},
+			wantName:   "vendor",
+		},
+		{
+			name:       "named_layer_with_prefix",
+			giveSource: Source{FS: vendorFS, Prefix: "lib", Layer: "vendor"},
+			wantName:   "lib.vendor",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := Build(tt.giveSource)
+			if err != nil {
+				t.Fatalf("Build() error = %v, want nil", err)
+			}
+
+			// Files are concatenated in walk order
+			want := "@layer " + tt.wantName + ";\n" +
+				"@layer " + tt.wantName + " {\n" +
+				"/* calendar */\n/* index */\n/* dark */\n/* light */\n/* normalize */\n" +
+				"}\n"
+			if got != want {
+				t.Errorf("Build() =\n%s\nwant\n%s", got, want)
+			}
+		})
+	}
+}
+
+func TestBuild_external_layers(t *testing.T) {
+	t.Parallel()
+
+	stylesFS := fstest.MapFS{
+		"reset.css": {Data: []byte("/* reset */")},
+	}
+	componentsFS := fstest.MapFS{
+		"button.css": {Data: []byte("/* button */")},
+	}
+
+	got, err := Build(
+		ExternalLayer("cms"),
+		Source{FS: stylesFS},
+		ExternalLayer("tw.utilities"),
+		Source{FS: componentsFS, Prefix: "comp"},
+	)
+	if err != nil {
+		t.Fatalf("Build() error = %v, want nil", err)
+	}
+
+	want := "@layer cms, reset, tw.utilities, comp.button;\n" +
+		"@layer reset {\n/* reset */\n}\n" +
+		"@layer comp.button {\n/* button */\n}\n"
+	if got != want {
+		t.Errorf("Build() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestBuild_external_layer_only(t *testing.T) {
+	t.Parallel()
+
+	got, err := Build(ExternalLayer("tw.base"), ExternalLayer("tw.utilities"))
+	if err != nil {
+		t.Fatalf("Build() error = %v, want nil", err)
+	}
+
+	want := "@layer tw.base, tw.utilities;\n"
+	if got != want {
+		t.Errorf("Build() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,22 @@
+package strata
+
+import (
+	"bytes"
+	"fmt"
+	"text/template"
+)
+
+// executeTemplate executes a *.css.tmpl file as a text/template with the
+// config's TemplateData and TemplateFuncs, returning the generated CSS.
+func (c *compiler) executeTemplate(filePath string, content []byte) ([]byte, error) {
+	tmpl, err := template.New(filePath).Funcs(c.config.TemplateFuncs).Parse(string(content))
+	if err != nil {
+		return nil, fmt.Errorf("parse template %s: %w", filePath, err)
+	}
+
+	var out bytes.Buffer
+	if err := tmpl.Execute(&out, c.config.TemplateData); err != nil {
+		return nil, fmt.Errorf("execute template %s: %w", filePath, err)
+	}
+	return out.Bytes(), nil
+}
@@ -0,0 +1,93 @@
+package strata
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"text/template"
+)
+
+func TestCompile_templates(t *testing.T) {
+	t.Parallel()
+
+	testFS := fstest.MapFS{
+		"brand.css.tmpl": {Data: []byte(":root { --brand: {{ .Brand }}; }")},
+		"layout/breakpoints.css.tmpl": {Data: []byte(
+			"{{ range .Breakpoints }}@media (min-width: {{ px .Width }}) { .{{ .Name }}\\:show { display: block; } }\n{{ end }}",
+		)},
+		"layout/grid.css":            {Data: []byte(".grid { display: grid; }")},
+		"components/_layer.css.tmpl": {Data: []byte("/* {{ .Brand }} */")},
+	}
+
+	type breakpoint struct {
+		Name  string
+		Width int
+	}
+	cfg := Config{
+		TemplateData: map[string]any{
+			"Brand":       "#3b82f6",
+			"Breakpoints": []breakpoint{{"md", 768}, {"lg", 1024}},
+		},
+		TemplateFuncs: template.FuncMap{
+			"px": func(n int) string { return strconv.Itoa(n) + "px" },
+		},
+	}
+
+	got, err := cfg.Build(Source{FS: testFS})
+	if err != nil {
+		t.Fatalf("Config.Build() error = %v, want nil", err)
+	}
+
+	want := "@layer brand, components, layout;\n" +
+		"@layer brand {\n:root { --brand: #3b82f6; }\n}\n" +
+		"@layer components {\n/* #3b82f6 */\n}\n" +
+		"@layer layout {\n" +
+		"@media (min-width: 768px) { .md\\:show { display: block; } }\n" +
+		"@media (min-width: 1024px) { .lg\\:show { display: block; } }\n" +
+		"\n" +
+		".grid { display: grid; }\n" +
+		"}\n"
+	if got != want {
+		t.Errorf("Config.Build() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestCompile_template_errors(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		giveCSS   string
+		wantError string
+	}{
+		{
+			name:      "parse_error",
+			giveCSS:   "{{ .Brand ",
+			wantError: "parse template theme/brand.css.tmpl",
+		},
+		{
+			name:      "execute_error",
+			giveCSS:   "{{ .Missing.Field }}",
+			wantError: "execute template theme/brand.css.tmpl",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			testFS := fstest.MapFS{
+				"theme/brand.css.tmpl": {Data: []byte(tt.giveCSS)},
+			}
+
+			_, err := Config{TemplateData: struct{ Brand string }{}}.Build(Source{FS: testFS})
+			if err == nil {
+				t.Fatal("Config.Build() error = nil, want error")
+			}
+			if !strings.Contains(err.Error(), tt.wantError) {
+				t.Errorf("Config.Build() error = %q, want error containing %q", err.Error(), tt.wantError)
+			}
+		})
+	}
+}
@@ -0,0 +1,404 @@
+package strata
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// tokenObject is a JSON object that preserves key order, so custom
+// properties are emitted in the order the design tokens file lists them.
+type tokenObject struct {
+	keys   []string
+	values map[string]any
+}
+
+// token is a design token flattened from a DTCG file.
+type token struct {
+	// path is the dot-separated token path (e.g., "color.primary").
+	path string
+
+	// typ is the token's $type, inherited from its groups if needed.
+	typ string
+
+	// value is the token's raw $value.
+	value any
+}
+
+// convertTokens converts a W3C Design Tokens (DTCG) JSON file to a :root
+// rule declaring one custom property per token.
+//
+// Group names and token names join with "-" to form the property name, so
+// {"color": {"primary": {"$value": "#3b82f6"}}} becomes --color-primary.
+// A group's $type applies to tokens that do not set their own. Aliases such
+// as "{color.primary}" become var(--color-primary) and must name a token in
+// the same file.
+func convertTokens(filePath string, content []byte) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(content))
+	dec.UseNumber()
+	root, err := decodeTokenJSON(dec)
+	if err != nil {
+		return nil, fmt.Errorf("parse tokens %s: %w", filePath, err)
+	}
+	group, ok := root.(*tokenObject)
+	if !ok {
+		return nil, fmt.Errorf("parse tokens %s: top level must be an object", filePath)
+	}
+
+	var tokens []token
+	flattenTokens(group, "", "", &tokens)
+	if len(tokens) == 0 {
+		return nil, nil
+	}
+
+	known := make(map[string]bool, len(tokens))
+	for _, t := range tokens {
+		known[t.path] = true
+	}
+
+	var out bytes.Buffer
+	out.WriteString(":root {\n")
+	for _, t := range tokens {
+		value, err := formatToken(t.typ, t.value, known)
+		if err != nil {
+			return nil, fmt.Errorf("tokens %s: %s: %w", filePath, t.path, err)
+		}
+		fmt.Fprintf(&out, "\t%s: %s;\n", tokenProperty(t.path), value)
+	}
+	out.WriteString("}\n")
+	return out.Bytes(), nil
+}
+
+// decodeTokenJSON decodes the next JSON value from dec, keeping object key
+// order. Objects decode as *tokenObject, arrays as []any and numbers as
+// json.Number.
+func decodeTokenJSON(dec *json.Decoder) (any, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+
+	switch tok {
+	case json.Delim('{'):
+		obj := &tokenObject{values: make(map[string]any)}
+		for dec.More() {
+			keyTok, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			key, _ := keyTok.(string)
+			value, err := decodeTokenJSON(dec)
+			if err != nil {
+				return nil, err
+			}
+			if _, dup := obj.values[key]; !dup {
+				obj.keys = append(obj.keys, key)
+			}
+			obj.values[key] = value
+		}
+		_, err := dec.Token() // closing brace
+		return obj, err
+	case json.Delim('['):
+		arr := []any{}
+		for dec.More() {
+			value, err := decodeTokenJSON(dec)
+			if err != nil {
+				return nil, err
+			}
+			arr = append(arr, value)
+		}
+		_, err := dec.Token() // closing bracket
+		return arr, err
+	default:
+		return tok, nil
+	}
+}
+
+// flattenTokens appends every token in group to tokens, depth-first in
+// file order. Keys starting with "$" are group properties, not children.
+func flattenTokens(group *tokenObject, prefix, inheritedType string, tokens *[]token) {
+	if typ, ok := group.values["$type"].(string); ok {
+		inheritedType = typ
+	}
+
+	for _, key := range group.keys {
+		if strings.HasPrefix(key, "$") {
+			continue
+		}
+		child, ok := group.values[key].(*tokenObject)
+		if !ok {
+			continue
+		}
+
+		childPath := key
+		if prefix != "" {
+			childPath = prefix + "." + key
+		}
+
+		value, isToken := child.values["$value"]
+		if !isToken {
+			flattenTokens(child, childPath, inheritedType, tokens)
+			continue
+		}
+
+		typ := inheritedType
+		if own, ok := child.values["$type"].(string); ok {
+			typ = own
+		}
+		*tokens = append(*tokens, token{path: childPath, typ: typ, value: value})
+	}
+}
+
+// tokenProperty converts a token path to its custom property name
+// (e.g., "color.primary" -> "--color-primary").
+func tokenProperty(tokenPath string) string {
+	name := strings.NewReplacer(".", "-", " ", "-").Replace(tokenPath)
+	return "--" + name
+}
+
+// formatToken converts a token value to CSS according to its type.
+func formatToken(typ string, value any, known map[string]bool) (string, error) {
+	// An alias may stand in for a value of any type
+	if s, ok := value.(string); ok {
+		return formatTokenString(s, known)
+	}
+
+	switch typ {
+	case "fontFamily":
+		return formatFontFamily(value, known)
+	case "cubicBezier":
+		return formatCubicBezier(value, known)
+	case "shadow":
+		return formatTokenList(value, known, func(v any) (string, error) {
+			return formatTokenFields(v, known, "offsetX", "offsetY", "blur", "spread", "color")
+		})
+	case "gradient":
+		return formatTokenList(value, known, func(v any) (string, error) {
+			return formatGradientStop(v, known)
+		})
+	case "border":
+		return formatTokenFields(value, known, "width", "style", "color")
+	case "transition":
+		return formatTokenFields(value, known, "duration", "timingFunction", "delay")
+	case "typography":
+		return formatTypography(value, known)
+	}
+
+	return formatTokenScalar(value, known)
+}
+
+// formatTokenString returns a string value with each alias, such as
+// "{color.primary}" in "1px solid {color.primary}", replaced by a var()
+// reference.
+func formatTokenString(s string, known map[string]bool) (string, error) {
+	var out strings.Builder
+	for {
+		open := strings.IndexByte(s, '{')
+		if open < 0 {
+			break
+		}
+		end := strings.IndexByte(s[open:], '}')
+		if end < 0 {
+			break
+		}
+		ref := s[open+1 : open+end]
+		if !known[ref] {
+			return "", fmt.Errorf("unresolved alias {%s}", ref)
+		}
+		out.WriteString(s[:open])
+		out.WriteString("var(" + tokenProperty(ref) + ")")
+		s = s[open+end+1:]
+	}
+	out.WriteString(s)
+	return out.String(), nil
+}
+
+// formatTokenScalar formats strings, numbers, and the {value, unit} and
+// {hex} objects used by dimension, duration and color tokens.
+func formatTokenScalar(value any, known map[string]bool) (string, error) {
+	switch v := value.(type) {
+	case string:
+		return formatTokenString(v, known)
+	case json.Number:
+		return v.String(), nil
+	case bool:
+		return fmt.Sprint(v), nil
+	case *tokenObject:
+		if n, ok := v.values["value"].(json.Number); ok {
+			unit, _ := v.values["unit"].(string)
+			return n.String() + unit, nil
+		}
+		if hex, ok := v.values["hex"].(string); ok {
+			return hex, nil
+		}
+		return "", fmt.Errorf("unsupported object value with keys %v", v.keys)
+	default:
+		return "", fmt.Errorf("unsupported value %v", value)
+	}
+}
+
+// formatFontFamily joins a font stack, quoting names that contain spaces.
+func formatFontFamily(value any, known map[string]bool) (string, error) {
+	names, ok := value.([]any)
+	if !ok {
+		return formatTokenScalar(value, known)
+	}
+	parts := make([]string, len(names))
+	for i, n := range names {
+		name, err := formatTokenScalar(n, known)
+		if err != nil {
+			return "", err
+		}
+		if strings.Contains(name, " ") && !strings.HasPrefix(name, "var(") {
+			name = `"` + name + `"`
+		}
+		parts[i] = name
+	}
+	return strings.Join(parts, ", "), nil
+}
+
+// formatCubicBezier formats a four-number array as cubic-bezier().
+func formatCubicBezier(value any, known map[string]bool) (string, error) {
+	points, ok := value.([]any)
+	if !ok || len(points) != 4 {
+		return "", errors.New("cubicBezier value must be an array of four numbers")
+	}
+	parts := make([]string, len(points))
+	for i, p := range points {
+		s, err := formatTokenScalar(p, known)
+		if err != nil {
+			return "", err
+		}
+		parts[i] = s
+	}
+	return "cubic-bezier(" + strings.Join(parts, ", ") + ")", nil
+}
+
+// formatTokenList formats a single value or an array of values with
+// format, joining array items with commas.
+func formatTokenList(value any, known map[string]bool, format func(any) (string, error)) (string, error) {
+	items, ok := value.([]any)
+	if !ok {
+		return format(value)
+	}
+	parts := make([]string, len(items))
+	for i, item := range items {
+		if s, ok := item.(string); ok {
+			ref, err := formatTokenString(s, known)
+			if err != nil {
+				return "", err
+			}
+			parts[i] = ref
+			continue
+		}
+		s, err := format(item)
+		if err != nil {
+			return "", err
+		}
+		parts[i] = s
+	}
+	return strings.Join(parts, ", "), nil
+}
+
+// formatTokenFields formats a composite object as its fields joined by
+// spaces, in the given order. Missing fields are skipped.
+func formatTokenFields(value any, known map[string]bool, fields ...string) (string, error) {
+	obj, ok := value.(*tokenObject)
+	if !ok {
+		return formatTokenScalar(value, known)
+	}
+	var parts []string
+	for _, field := range fields {
+		v, ok := obj.values[field]
+		if !ok {
+			continue
+		}
+		s, err := formatTokenField(field, v, known)
+		if err != nil {
+			return "", err
+		}
+		parts = append(parts, s)
+	}
+	if obj.values["inset"] == true {
+		parts = append([]string{"inset"}, parts...)
+	}
+	return strings.Join(parts, " "), nil
+}
+
+// formatTokenField formats one field of a composite token.
+func formatTokenField(field string, value any, known map[string]bool) (string, error) {
+	switch field {
+	case "fontFamily":
+		return formatFontFamily(value, known)
+	case "timingFunction":
+		if _, ok := value.([]any); ok {
+			return formatCubicBezier(value, known)
+		}
+	}
+	return formatTokenScalar(value, known)
+}
+
+// formatGradientStop formats a {color, position} gradient stop, with the
+// position as a percentage.
+func formatGradientStop(value any, known map[string]bool) (string, error) {
+	obj, ok := value.(*tokenObject)
+	if !ok {
+		return formatTokenScalar(value, known)
+	}
+	color, err := formatTokenScalar(obj.values["color"], known)
+	if err != nil {
+		return "", err
+	}
+	pos, ok := obj.values["position"].(json.Number)
+	if !ok {
+		return color, nil
+	}
+	f, err := pos.Float64()
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s %g%%", color, f*100), nil
+}
+
+// formatTypography formats a typography token as a font shorthand:
+// weight size/line-height family. Missing fields are skipped.
+func formatTypography(value any, known map[string]bool) (string, error) {
+	obj, ok := value.(*tokenObject)
+	if !ok {
+		return formatTokenScalar(value, known)
+	}
+
+	weight, err := formatTokenFields(obj, known, "fontWeight")
+	if err != nil {
+		return "", err
+	}
+	size, err := formatTokenFields(obj, known, "fontSize")
+	if err != nil {
+		return "", err
+	}
+	lineHeight, err := formatTokenFields(obj, known, "lineHeight")
+	if err != nil {
+		return "", err
+	}
+	family, err := formatTokenFields(obj, known, "fontFamily")
+	if err != nil {
+		return "", err
+	}
+
+	if lineHeight != "" {
+		size += "/" + lineHeight
+	}
+	var parts []string
+	for _, part := range []string{weight, size, family} {
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return strings.Join(parts, " "), nil
+}
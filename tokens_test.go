@@ -0,0 +1,183 @@
+package strata
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestConvertTokens(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		giveJSON string
+		want     string
+	}{
+		{
+			name: "groups_and_order",
+			giveJSON: `{
+				"color": {
+					"$type": "color",
+					"primary": {"$value": "#3b82f6", "$description": "Brand"},
+					"border": {"$value": "#e5e7eb"}
+				},
+				"radius": {"sm": {"$type": "dimension", "$value": "4px"}}
+			}`,
+			want: ":root {\n" +
+				"\t--color-primary: #3b82f6;\n" +
+				"\t--color-border: #e5e7eb;\n" +
+				"\t--radius-sm: 4px;\n" +
+				"}\n",
+		},
+		{
+			name: "aliases",
+			giveJSON: `{
+				"color": {"blue": {"$value": "#3b82f6"}},
+				"button": {"bg": {"$type": "color", "$value": "{color.blue}"}}
+			}`,
+			want: ":root {\n" +
+				"\t--color-blue: #3b82f6;\n" +
+				"\t--button-bg: var(--color-blue);\n" +
+				"}\n",
+		},
+		{
+			name: "embedded_aliases",
+			giveJSON: `{
+				"color": {"p": {"$value": "#3b82f6"}, "q": {"$value": "#fff"}},
+				"border": {"b": {"$value": "1px solid {color.p}"}},
+				"gradient": {"g": {"$value": "linear-gradient({color.p}, {color.q})"}}
+			}`,
+			want: ":root {\n" +
+				"\t--color-p: #3b82f6;\n" +
+				"\t--color-q: #fff;\n" +
+				"\t--border-b: 1px solid var(--color-p);\n" +
+				"\t--gradient-g: linear-gradient(var(--color-p), var(--color-q));\n" +
+				"}\n",
+		},
+		{
+			name: "numbers_and_objects",
+			giveJSON: `{
+				"weight": {"$type": "fontWeight", "$value": 600},
+				"spacing": {"$type": "dimension", "$value": {"value": 1.5, "unit": "rem"}},
+				"fast": {"$type": "duration", "$value": {"value": 200, "unit": "ms"}},
+				"accent": {"$type": "color", "$value": {"colorSpace": "srgb", "components": [1, 0, 0], "hex": "#ff0000"}}
+			}`,
+			want: ":root {\n" +
+				"\t--weight: 600;\n" +
+				"\t--spacing: 1.5rem;\n" +
+				"\t--fast: 200ms;\n" +
+				"\t--accent: #ff0000;\n" +
+				"}\n",
+		},
+		{
+			name: "composite_types",
+			giveJSON: `{
+				"font": {"$type": "fontFamily", "$value": ["Inter Variable", "system-ui"]},
+				"ease": {"$type": "cubicBezier", "$value": [0.4, 0, 0.2, 1]},
+				"shadow": {"$type": "shadow", "$value": {"color": "#0000001a", "offsetX": "0", "offsetY": "2px", "blur": "4px", "spread": "0"}},
+				"border": {"$type": "border", "$value": {"color": "#e5e7eb", "width": "1px", "style": "solid"}},
+				"fade": {"$type": "gradient", "$value": [{"color": "#fff", "position": 0}, {"color": "#000", "position": 1}]},
+				"heading": {"$type": "typography", "$value": {"fontFamily": ["Inter"], "fontSize": "2rem", "fontWeight": 700, "lineHeight": 1.2}}
+			}`,
+			want: ":root {\n" +
+				"\t--font: \"Inter Variable\", system-ui;\n" +
+				"\t--ease: cubic-bezier(0.4, 0, 0.2, 1);\n" +
+				"\t--shadow: 0 2px 4px 0 #0000001a;\n" +
+				"\t--border: 1px solid #e5e7eb;\n" +
+				"\t--fade: #fff 0%, #000 100%;\n" +
+				"\t--heading: 700 2rem/1.2 Inter;\n" +
+				"}\n",
+		},
+		{
+			name:     "empty",
+			giveJSON: `{}`,
+			want:     "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := convertTokens("design.tokens.json", []byte(tt.giveJSON))
+			if err != nil {
+				t.Fatalf("convertTokens() error = %v, want nil", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("convertTokens() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConvertTokens_errors(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		giveJSON  string
+		wantError string
+	}{
+		{
+			name:      "unresolved_alias",
+			giveJSON:  `{"button": {"bg": {"$value": "{color.missing}"}}}`,
+			wantError: "button.bg: unresolved alias {color.missing}",
+		},
+		{
+			name:      "unresolved_embedded_alias",
+			giveJSON:  `{"color": {"p": {"$value": "#000"}}, "b": {"$value": "1px solid {color.p} {color.x}"}}`,
+			wantError: "b: unresolved alias {color.x}",
+		},
+		{
+			name:      "unresolved_alias_in_composite",
+			giveJSON:  `{"b": {"$type": "border", "$value": {"color": "{nope}", "width": "1px", "style": "solid"}}}`,
+			wantError: "unresolved alias {nope}",
+		},
+		{
+			name:      "invalid_json",
+			giveJSON:  `{"color": `,
+			wantError: "parse tokens design.tokens.json",
+		},
+		{
+			name:      "not_an_object",
+			giveJSON:  `[1, 2]`,
+			wantError: "top level must be an object",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := convertTokens("design.tokens.json", []byte(tt.giveJSON))
+			if err == nil {
+				t.Fatal("convertTokens() error = nil, want error")
+			}
+			if !strings.Contains(err.Error(), tt.wantError) {
+				t.Errorf("convertTokens() error = %q, want error containing %q", err.Error(), tt.wantError)
+			}
+		})
+	}
+}
+
+func TestBuild_tokens_file(t *testing.T) {
+	t.Parallel()
+
+	testFS := fstest.MapFS{
+		"tokens.tokens.json": {Data: []byte(`{"color": {"primary": {"$value": "#3b82f6"}}}`)},
+		"reset.css":          {Data: []byte("/* reset */")},
+	}
+
+	got, err := Build(Source{FS: testFS})
+	if err != nil {
+		t.Fatalf("Build() error = %v, want nil", err)
+	}
+
+	want := "@layer reset, tokens;\n" +
+		"@layer reset {\n/* reset */\n}\n" +
+		"@layer tokens {\n:root {\n\t--color-primary: #3b82f6;\n}\n\n}\n"
+	if got != want {
+		t.Errorf("Build() =\n%s\nwant\n%s", got, want)
+	}
+}
@@ -0,0 +1,36 @@
+package strata
+
+import "fmt"
+
+// Transformer processes a file's content before it is appended to its layer.
+//
+// Transform receives the file's path within its Source.FS, the name of the
+// layer it belongs to (empty for unlayered sources) and its content, and
+// returns the new content. Use transformers for token substitution, URL
+// rewriting or custom lint checks.
+type Transformer interface {
+	Transform(filePath, layerName string, content []byte) ([]byte, error)
+}
+
+// TransformFunc adapts an ordinary function to the Transformer interface.
+type TransformFunc func(filePath, layerName string, content []byte) ([]byte, error)
+
+// Transform calls f(filePath, layerName, content).
+func (f TransformFunc) Transform(filePath, layerName string, content []byte) ([]byte, error) {
+	return f(filePath, layerName, content)
+}
+
+// transform runs the source's transformers, then the config's, over a
+// file's content. Errors are wrapped with the file path.
+func (c *compiler) transform(s Source, filePath, layerName string, content []byte) ([]byte, error) {
+	for _, chain := range [][]Transformer{s.Transformers, c.config.Transformers} {
+		for _, t := range chain {
+			var err error
+			content, err = t.Transform(filePath, layerName, content)
+			if err != nil {
+				return nil, fmt.Errorf("transform %s: %w", filePath, err)
+			}
+		}
+	}
+	return content, nil
+}
@@ -0,0 +1,70 @@
+package strata
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestCompile_transformers(t *testing.T) {
+	t.Parallel()
+
+	testFS := fstest.MapFS{
+		"reset.css":        {Data: []byte("/* $brand */")},
+		"components/a.css": {Data: []byte("/* a */")},
+	}
+
+	var calls []string
+	record := TransformFunc(func(filePath, layerName string, content []byte) ([]byte, error) {
+		calls = append(calls, filePath+"@"+layerName)
+		return content, nil
+	})
+	brand := TransformFunc(func(_, _ string, content []byte) ([]byte, error) {
+		return bytes.ReplaceAll(content, []byte("$brand"), []byte("blue")), nil
+	})
+	suffix := TransformFunc(func(_, layerName string, content []byte) ([]byte, error) {
+		return append(content, []byte(" /* "+layerName+" */")...), nil
+	})
+
+	got, err := Config{Transformers: []Transformer{suffix}}.Build(Source{
+		FS:           testFS,
+		Transformers: []Transformer{record, brand},
+	})
+	if err != nil {
+		t.Fatalf("Config.Build() error = %v, want nil", err)
+	}
+
+	want := "@layer components, reset;\n" +
+		"@layer components {\n/* a */ /* components */\n}\n" +
+		"@layer reset {\n/* blue */ /* reset */\n}\n"
+	if got != want {
+		t.Errorf("Config.Build() =\n%s\nwant\n%s", got, want)
+	}
+
+	wantCalls := "components/a.css@components reset.css@reset"
+	if strings.Join(calls, " ") != wantCalls {
+		t.Errorf("Transformer calls = %v, want %s", calls, wantCalls)
+	}
+}
+
+func TestCompile_transformer_error(t *testing.T) {
+	t.Parallel()
+
+	testFS := fstest.MapFS{
+		"base/bad.css": {Data: []byte("x")},
+	}
+	errLint := errors.New("lint failed")
+	failing := TransformFunc(func(_, _ string, _ []byte) ([]byte, error) {
+		return nil, errLint
+	})
+
+	_, err := Config{Transformers: []Transformer{failing}}.Compile(Source{FS: testFS})
+	if !errors.Is(err, errLint) {
+		t.Fatalf("Config.Compile() error = %v, want %v", err, errLint)
+	}
+	if !strings.Contains(err.Error(), "base/bad.css") {
+		t.Errorf("Config.Compile() error = %q, want error containing path", err.Error())
+	}
+}
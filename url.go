@@ -0,0 +1,227 @@
+package strata
+
+import (
+	"fmt"
+	"io/fs"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// urlRef is a url() or image-set() reference found in CSS.
+type urlRef struct {
+	// start and end delimit the reference text, excluding any quotes.
+	start, end int
+
+	// url is the reference as written, without quotes. CSS escapes and
+	// percent-encoding are left in place; see refFilePath.
+	url string
+}
+
+// findURLRefs returns the references in css made by url() functions and
+// by quoted strings directly inside image-set(), in source order.
+// Comments and other strings are skipped.
+func findURLRefs(css string) []urlRef {
+	var refs []urlRef
+	depth := 0
+	var imageSets []int // paren depths of enclosing image-set() calls
+	for pos := 0; pos < len(css); {
+		c := css[pos]
+		switch {
+		case c == '/' && strings.HasPrefix(css[pos:], "/*"):
+			pos = skipComment(css, pos)
+		case c == '"' || c == '\'':
+			end := skipString(css, pos)
+			if len(imageSets) > 0 && imageSets[len(imageSets)-1] == depth && end-1 > pos && css[end-1] == c {
+				refs = append(refs, urlRef{start: pos + 1, end: end - 1, url: css[pos+1 : end-1]})
+			}
+			pos = end
+		case c == '(':
+			depth++
+			pos++
+		case c == ')':
+			if len(imageSets) > 0 && imageSets[len(imageSets)-1] == depth {
+				imageSets = imageSets[:len(imageSets)-1]
+			}
+			depth--
+			pos++
+		case isNameStart(c) || c == '-':
+			if pos > 0 && isNameChar(css[pos-1]) {
+				pos++
+				continue
+			}
+			name := strings.ToLower(css[pos:scanIdent(css, pos, len(css))])
+			next := pos + len(name)
+			switch {
+			case next >= len(css) || css[next] != '(':
+				pos = next
+			case name == "url":
+				ref, end := scanURL(css, next+1)
+				if ref.end > ref.start {
+					refs = append(refs, ref)
+				}
+				pos = end
+			case name == "image-set" || name == "-webkit-image-set":
+				depth++
+				imageSets = append(imageSets, depth)
+				pos = next + 1
+			default:
+				pos = next
+			}
+		default:
+			pos++
+		}
+	}
+	return refs
+}
+
+// scanURL parses the argument of a url() function starting at pos, just
+// after the opening parenthesis. It returns the reference and the position
+// after the closing parenthesis.
+func scanURL(css string, pos int) (urlRef, int) {
+	pos = skipSpaces(css, pos)
+	if pos < len(css) && (css[pos] == '"' || css[pos] == '\'') {
+		end := skipString(css, pos)
+		ref := urlRef{start: pos + 1, end: max(end-1, pos+1), url: css[pos+1 : max(end-1, pos+1)]}
+		closing := strings.IndexByte(css[end:], ')')
+		if closing < 0 {
+			return ref, len(css)
+		}
+		return ref, end + closing + 1
+	}
+
+	closing := strings.IndexByte(css[pos:], ')')
+	if closing < 0 {
+		return urlRef{}, len(css)
+	}
+	end := pos + closing
+	trimmed := strings.TrimRight(css[pos:end], " \t\n\r\f")
+	return urlRef{start: pos, end: pos + len(trimmed), url: trimmed}, end + 1
+}
+
+// refFilePath decodes the path of a reference as written in CSS, undoing
+// CSS escapes and then percent-encoding, so "my\ icon.png" and
+// "my%20icon.png" both name the file "my icon.png". Invalid
+// percent-encoding is left as written.
+func refFilePath(ref string) string {
+	p := cssUnescape(ref)
+	if decoded, err := url.PathUnescape(p); err == nil {
+		return decoded
+	}
+	return p
+}
+
+// skipSpaces returns the position of the next non-whitespace character.
+func skipSpaces(s string, pos int) int {
+	for pos < len(s) && isSpace(s[pos]) {
+		pos++
+	}
+	return pos
+}
+
+// isRelativeURL reports whether ref is relative to the referencing file:
+// not absolute, not protocol-relative, without a scheme (such as data: or
+// https:), and not a bare fragment.
+func isRelativeURL(ref string) bool {
+	switch {
+	case ref == "", strings.HasPrefix(ref, "/"), strings.HasPrefix(ref, "#"):
+		return false
+	}
+	colon := strings.IndexByte(ref, ':')
+	return colon < 0 || strings.ContainsAny(ref[:colon], "/?#")
+}
+
+// splitURLSuffix splits a reference into its path and any query or
+// fragment suffix.
+func splitURLSuffix(ref string) (refPath, suffix string) {
+	if i := strings.IndexAny(ref, "?#"); i >= 0 {
+		return ref[:i], ref[i:]
+	}
+	return ref, ""
+}
+
+// joinURL joins URL path elements onto base. A base that is empty yields
+// a relative path; an absolute elems[0] ignores base.
+func joinURL(base string, elems ...string) string {
+	if len(elems) > 0 && strings.HasPrefix(elems[0], "/") {
+		base = ""
+	}
+	joined := path.Join(elems...)
+	if base == "" {
+		return joined
+	}
+	return strings.TrimSuffix(base, "/") + "/" + joined
+}
+
+// rewriteURLs replaces every url() and image-set() reference in css with
+// the result of rewrite, which receives the reference and its 1-based line.
+func rewriteURLs(css string, rewrite func(ref string, line int) (string, error)) (string, error) {
+	refs := findURLRefs(css)
+	if len(refs) == 0 {
+		return css, nil
+	}
+
+	var out strings.Builder
+	last, line := 0, 1
+	for _, ref := range refs {
+		line += strings.Count(css[last:ref.start], "\n")
+		replacement, err := rewrite(ref.url, line)
+		if err != nil {
+			return "", err
+		}
+		out.WriteString(css[last:ref.start])
+		out.WriteString(replacement)
+		last = ref.end
+	}
+	out.WriteString(css[last:])
+	return out.String(), nil
+}
+
+// rewriteFileURLs rewrites the relative references in a file. Each
+// reference is resolved against the file's directory within its
+// Source.FS, then inlined as a data URI (see Config.InlineLimit) or
+// replaced by a hashed asset (see Config.HashAssets). Other references are
+// joined onto the Source URL and Config.BaseURL with Config.RewriteURLs,
+// and otherwise left as written.
+func (c *compiler) rewriteFileURLs(s Source, filePath string, content []byte) ([]byte, error) {
+	css, err := rewriteURLs(string(content), func(ref string, line int) (string, error) {
+		if !isRelativeURL(ref) {
+			return ref, nil
+		}
+		refPath, suffix := splitURLSuffix(ref)
+		assetPath := path.Join(path.Dir(filePath), refFilePath(refPath))
+
+		// Rewritten references keep their encoding as written
+		kept := ref
+		if c.config.RewriteURLs {
+			kept = joinURL(c.config.BaseURL, s.URL, path.Dir(filePath), refPath) + suffix
+		}
+		if !c.config.HashAssets && c.config.InlineLimit <= 0 {
+			return kept, nil
+		}
+
+		data, err := fs.ReadFile(s.FS, assetPath)
+		switch {
+		case err == nil:
+		case c.config.HashAssets:
+			return "", fmt.Errorf("%s:%d: asset %s: %w", filePath, line, ref, err)
+		default:
+			return kept, nil // only inlining, so a missing asset is served separately
+		}
+
+		// Fragments usually address parts of an SVG sprite, which only
+		// work against the file itself
+		if int64(len(data)) < c.config.InlineLimit && !strings.Contains(suffix, "#") {
+			return dataURI(assetPath, data), nil
+		}
+		if c.config.HashAssets {
+			name := (&url.URL{Path: c.addAsset(assetPath, data)}).EscapedPath()
+			return joinURL(c.config.BaseURL, name) + suffix, nil
+		}
+		return kept, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return []byte(css), nil
+}
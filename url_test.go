@@ -0,0 +1,228 @@
+package strata
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestFindURLRefs(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		giveCSS string
+		want    []string
+	}{
+		{
+			name:    "unquoted",
+			giveCSS: "a { background: url(./bg.png); }",
+			want:    []string{"./bg.png"},
+		},
+		{
+			name:    "quoted_with_spaces",
+			giveCSS: `a { background: url( "img/a b.png" ) no-repeat; }`,
+			want:    []string{"img/a b.png"},
+		},
+		{
+			name:    "uppercase_function",
+			giveCSS: "a { background: URL(x.png); }",
+			want:    []string{"x.png"},
+		},
+		{
+			name:    "font_face_sources",
+			giveCSS: `@font-face { src: url('f.woff2') format("woff2"), url(f.woff) format("woff"); }`,
+			want:    []string{"f.woff2", "f.woff"},
+		},
+		{
+			name:    "image_set",
+			giveCSS: `a { background: image-set("a.png" 1x, url(b.png) 2x, "c.avif" type("image/avif")); }`,
+			want:    []string{"a.png", "b.png", "c.avif"},
+		},
+		{
+			name:    "webkit_image_set",
+			giveCSS: `a { background: -webkit-image-set("a.png" 1x); }`,
+			want:    []string{"a.png"},
+		},
+		{
+			name:    "data_url_with_semicolon",
+			giveCSS: "a { background: url(data:image/png;base64,AAA=); }",
+			want:    []string{"data:image/png;base64,AAA="},
+		},
+		{
+			name:    "ignores_comments_strings_and_lookalikes",
+			giveCSS: `/* url(no.png) */ a::after { content: "url(no.png)"; --my-url: x; b: my-url(no.png); }`,
+			want:    nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var got []string
+			for _, ref := range findURLRefs(tt.giveCSS) {
+				got = append(got, ref.url)
+				if tt.giveCSS[ref.start:ref.end] != ref.url {
+					t.Errorf("findURLRefs() range %q, want %q", tt.giveCSS[ref.start:ref.end], ref.url)
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("findURLRefs(%q) = %q, want %q", tt.giveCSS, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsRelativeURL(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		give string
+		want bool
+	}{
+		{give: "bg.png", want: true},
+		{give: "./bg.png", want: true},
+		{give: "../shared/bg.png", want: true},
+		{give: "img/a:b.png", want: true},
+		{give: "/static/bg.png", want: false},
+		{give: "//cdn.example.com/bg.png", want: false},
+		{give: "https://example.com/bg.png", want: false},
+		{give: "data:image/svg+xml,<svg/>", want: false},
+		{give: "#gradient", want: false},
+		{give: "", want: false},
+	}
+
+	for _, tt := range tests {
+		if got := isRelativeURL(tt.give); got != tt.want {
+			t.Errorf("isRelativeURL(%q) = %v, want %v", tt.give, got, tt.want)
+		}
+	}
+}
+
+func TestRefFilePath(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		give string
+		want string
+	}{
+		{give: "img/bg.png", want: "img/bg.png"},
+		{give: `my\ icon.png`, want: "my icon.png"},
+		{give: `\31 0.png`, want: "10.png"},
+		{give: "bg%20x.png", want: "bg x.png"},
+		{give: "100%.png", want: "100%.png"},
+	}
+
+	for _, tt := range tests {
+		if got := refFilePath(tt.give); got != tt.want {
+			t.Errorf("refFilePath(%q) = %q, want %q", tt.give, got, tt.want)
+		}
+	}
+}
+
+func TestCompile_encoded_urls(t *testing.T) {
+	t.Parallel()
+
+	icon := []byte("png")
+	testFS := fstest.MapFS{
+		"card/card.css": {Data: []byte(
+			".a { background: url(my\\ icon.png); }\n" +
+				".b { background: url(\"my%20icon.png\"); }\n",
+		)},
+		"card/my icon.png": {Data: icon},
+	}
+
+	got, err := Config{HashAssets: true, BaseURL: "/static"}.Compile(Source{FS: testFS})
+	if err != nil {
+		t.Fatalf("Config.Compile() error = %v, want nil", err)
+	}
+
+	name := "my icon." + contentHash(icon) + ".png"
+	if len(got.Assets) != 1 || got.Assets[name] == nil {
+		t.Errorf("Result.Assets keys = %v, want %s", mapKeys(got.Assets), name)
+	}
+	want := "/static/my%20icon." + contentHash(icon) + ".png"
+	if css := got.CSS(); strings.Count(css, want) != 2 {
+		t.Errorf("Result.CSS() = %q, want both references rewritten to %s", css, want)
+	}
+
+	got, err = Config{RewriteURLs: true, BaseURL: "/static"}.Compile(Source{FS: testFS})
+	if err != nil {
+		t.Fatalf("Config.Compile() error = %v, want nil", err)
+	}
+	for _, want := range []string{`url(/static/card/my\ icon.png)`, `url("/static/card/my%20icon.png")`} {
+		if css := got.CSS(); !strings.Contains(css, want) {
+			t.Errorf("Result.CSS() missing %q, got:\n%s", want, css)
+		}
+	}
+}
+
+func TestCompile_rewrite_urls(t *testing.T) {
+	t.Parallel()
+
+	componentsFS := fstest.MapFS{
+		"card/card.css": {Data: []byte(
+			".card { background: url(./bg.png); }\n" +
+				".card-icon { background: image-set(\"icons/a.png\" 1x, url('icons/a@2x.png?v=2#x') 2x); }\n" +
+				".card-logo { background: url(/static/logo.svg), url(data:image/png;base64,AA==); }\n" +
+				".card-shared { background: url(../shared/dots.svg); }\n",
+		)},
+	}
+
+	tests := []struct {
+		name       string
+		giveConfig Config
+		giveURL    string
+		want       string
+	}{
+		{
+			name:       "relative_to_stylesheet",
+			giveConfig: Config{RewriteURLs: true},
+			giveURL:    "components",
+			want: ".card { background: url(components/card/bg.png); }\n" +
+				".card-icon { background: image-set(\"components/card/icons/a.png\" 1x, url('components/card/icons/a@2x.png?v=2#x') 2x); }\n" +
+				".card-logo { background: url(/static/logo.svg), url(data:image/png;base64,AA==); }\n" +
+				".card-shared { background: url(components/shared/dots.svg); }\n",
+		},
+		{
+			name:       "base_url",
+			giveConfig: Config{RewriteURLs: true, BaseURL: "https://cdn.example.com/static/"},
+			giveURL:    "components",
+			want: ".card { background: url(https://cdn.example.com/static/components/card/bg.png); }\n" +
+				".card-icon { background: image-set(\"https://cdn.example.com/static/components/card/icons/a.png\" 1x, url('https://cdn.example.com/static/components/card/icons/a@2x.png?v=2#x') 2x); }\n" +
+				".card-logo { background: url(/static/logo.svg), url(data:image/png;base64,AA==); }\n" +
+				".card-shared { background: url(https://cdn.example.com/static/components/shared/dots.svg); }\n",
+		},
+		{
+			name:       "absolute_source_url",
+			giveConfig: Config{RewriteURLs: true, BaseURL: "/ignored"},
+			giveURL:    "/assets/comp",
+			want: ".card { background: url(/assets/comp/card/bg.png); }\n" +
+				".card-icon { background: image-set(\"/assets/comp/card/icons/a.png\" 1x, url('/assets/comp/card/icons/a@2x.png?v=2#x') 2x); }\n" +
+				".card-logo { background: url(/static/logo.svg), url(data:image/png;base64,AA==); }\n" +
+				".card-shared { background: url(/assets/comp/shared/dots.svg); }\n",
+		},
+		{
+			name:       "disabled",
+			giveConfig: Config{BaseURL: "/static"},
+			giveURL:    "components",
+			want:       string(componentsFS["card/card.css"].Data),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := tt.giveConfig.Compile(Source{FS: componentsFS, URL: tt.giveURL})
+			if err != nil {
+				t.Fatalf("Config.Compile() error = %v, want nil", err)
+			}
+			if content := got.Layers[0].Content; content != tt.want+"\n" {
+				t.Errorf("Config.Compile() content =\n%s\nwant\n%s", content, tt.want)
+			}
+		})
+	}
+}